@@ -17,20 +17,273 @@
 package retrieval
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethersphere/swarm/network"
 )
 
-// Peer wraps BzzPeer with a contextual logger for this peer
+// requestTimeout bounds how long a request may stay in the in-flight
+// map before it is considered abandoned and evicted, freeing it up
+// for a retry to be tracked under the same address.
+const requestTimeout = 30 * time.Second
+
+var (
+	metricsRequestsSent      = metrics.NewRegisteredMeter("network.retrieval.peer.requests.sent", nil)
+	metricsRequestsDelivered = metrics.NewRegisteredMeter("network.retrieval.peer.requests.delivered", nil)
+	metricsRequestsFailed    = metrics.NewRegisteredCounter("network.retrieval.peer.requests.failed", nil)
+	metricsRequestsTimedOut  = metrics.NewRegisteredCounter("network.retrieval.peer.requests.timedout", nil)
+	metricsRequestsThrottled = metrics.NewRegisteredCounter("network.retrieval.peer.requests.throttled", nil)
+	metricsBytesIn           = metrics.NewRegisteredCounter("network.retrieval.peer.bytes.in", nil)
+	metricsBytesOut          = metrics.NewRegisteredCounter("network.retrieval.peer.bytes.out", nil)
+	metricsRequestLatency    = metrics.NewRegisteredHistogram("network.retrieval.peer.requests.latency", nil, metrics.NewExpDecaySample(1028, 0.015))
+)
+
+// latencyDecayAlpha{1,5,15}m are the ExpDecaySample decay constants
+// used for a Peer's three rolling latency histograms. A larger alpha
+// discounts older samples faster, so the three reservoirs stay
+// biased towards request latencies observed over roughly the last
+// 1, 5 and 15 minutes of traffic respectively, the same way a load
+// average tracks recent activity at different timescales.
+const (
+	latencyDecayAlpha1m  = 0.30
+	latencyDecayAlpha5m  = 0.05
+	latencyDecayAlpha15m = 0.015
+)
+
+// newLatencyHistograms returns the three rolling latency histograms
+// embedded in every Peer.
+func newLatencyHistograms() (m1, m5, m15 metrics.Histogram) {
+	return metrics.NewHistogram(metrics.NewExpDecaySample(1028, latencyDecayAlpha1m)),
+		metrics.NewHistogram(metrics.NewExpDecaySample(1028, latencyDecayAlpha5m)),
+		metrics.NewHistogram(metrics.NewExpDecaySample(1028, latencyDecayAlpha15m))
+}
+
+// now is a variable so that it can be overridden in tests.
+var now = time.Now
+
+// Peer wraps BzzPeer with a contextual logger for this peer, and
+// the per-peer retrieval accounting used by the protocol handler:
+// outstanding request tracking, rate limiting and metrics.
 type Peer struct {
 	*network.BzzPeer
 	logger log.Logger
+
+	chunksRequested uint64
+	chunksDelivered uint64
+	bytesIn         uint64
+	bytesOut        uint64
+
+	mu       sync.Mutex
+	inflight map[string]inflightRequest
+
+	// latency1m, latency5m and latency15m are this peer's own
+	// rolling latency histograms, each with a different exp-decay
+	// rate so that Stats can report how its response times are
+	// trending over the last 1, 5 and 15 minutes.
+	latency1m  metrics.Histogram
+	latency5m  metrics.Histogram
+	latency15m metrics.Histogram
+
+	limiter *leakyBucket
+}
+
+// inflightRequest records the point in time a request for a chunk
+// address was sent, so that TrackRequest's done closure can derive
+// its latency and sweepExpiredLocked can evict it if it never
+// completes.
+type inflightRequest struct {
+	sent     time.Time
+	deadline time.Time
+}
+
+// PeerStats is a snapshot of a Peer's retrieval accounting, as
+// returned by Peer.Stats.
+type PeerStats struct {
+	ChunksRequested uint64
+	ChunksDelivered uint64
+	BytesIn         uint64
+	BytesOut        uint64
+	InFlight        int
+
+	// Latency1m, Latency5m and Latency15m are the mean request
+	// latency observed by this peer's rolling histograms, biased
+	// towards the last 1, 5 and 15 minutes of traffic respectively.
+	// They are zero until this peer has completed at least one
+	// request.
+	Latency1m  time.Duration
+	Latency5m  time.Duration
+	Latency15m time.Duration
 }
 
 // NewPeer is the constructor for Peer
 func NewPeer(peer *network.BzzPeer) *Peer {
+	latency1m, latency5m, latency15m := newLatencyHistograms()
 	return &Peer{
-		BzzPeer: peer,
-		logger:  log.New("peer", peer.ID()),
+		BzzPeer:    peer,
+		logger:     log.New("peer", peer.ID()),
+		inflight:   make(map[string]inflightRequest),
+		latency1m:  latency1m,
+		latency5m:  latency5m,
+		latency15m: latency15m,
+		limiter:    newLeakyBucket(peerRequestRate, peerRequestBurst),
+	}
+}
+
+// TrackRequest records that a request for the chunk at addr has just
+// been sent to this peer, evicting any previously tracked requests
+// that have exceeded requestTimeout. The returned done function must
+// be called exactly once, with the error, if any, encountered while
+// serving the request, to resolve the in-flight entry and record its
+// latency and outcome.
+func (p *Peer) TrackRequest(addr []byte) (done func(err error)) {
+	key := string(addr)
+	sent := now()
+
+	p.mu.Lock()
+	p.sweepExpiredLocked(sent)
+	p.inflight[key] = inflightRequest{
+		sent:     sent,
+		deadline: sent.Add(requestTimeout),
+	}
+	p.mu.Unlock()
+
+	atomic.AddUint64(&p.chunksRequested, 1)
+	metricsRequestsSent.Mark(1)
+
+	return func(err error) {
+		p.mu.Lock()
+		req, ok := p.inflight[key]
+		if ok {
+			delete(p.inflight, key)
+		}
+		p.mu.Unlock()
+		if !ok {
+			// already evicted by sweepExpiredLocked or a duplicate done call
+			return
+		}
+
+		latency := int64(now().Sub(req.sent))
+		metricsRequestLatency.Update(latency)
+		p.latency1m.Update(latency)
+		p.latency5m.Update(latency)
+		p.latency15m.Update(latency)
+		if err != nil {
+			metricsRequestsFailed.Inc(1)
+			p.logger.Debug("retrieval request failed", "addr", fmt.Sprintf("%x", addr), "err", err)
+			return
+		}
+		atomic.AddUint64(&p.chunksDelivered, 1)
+		metricsRequestsDelivered.Mark(1)
+	}
+}
+
+// sweepExpiredLocked removes requests that have been in flight past
+// their deadline, counting them as timeouts. Callers must hold p.mu.
+func (p *Peer) sweepExpiredLocked(at time.Time) {
+	for key, req := range p.inflight {
+		if at.After(req.deadline) {
+			delete(p.inflight, key)
+			metricsRequestsTimedOut.Inc(1)
+		}
+	}
+}
+
+// AddBytesIn accounts for bytes received from this peer, such as
+// delivered chunk data.
+func (p *Peer) AddBytesIn(n int) {
+	atomic.AddUint64(&p.bytesIn, uint64(n))
+	metricsBytesIn.Inc(int64(n))
+}
+
+// AddBytesOut accounts for bytes sent to this peer, such as a
+// retrieval request.
+func (p *Peer) AddBytesOut(n int) {
+	atomic.AddUint64(&p.bytesOut, uint64(n))
+	metricsBytesOut.Inc(int64(n))
+}
+
+// Stats returns a snapshot of the peer's current retrieval counters.
+func (p *Peer) Stats() PeerStats {
+	p.mu.Lock()
+	inFlight := len(p.inflight)
+	p.mu.Unlock()
+
+	return PeerStats{
+		ChunksRequested: atomic.LoadUint64(&p.chunksRequested),
+		ChunksDelivered: atomic.LoadUint64(&p.chunksDelivered),
+		BytesIn:         atomic.LoadUint64(&p.bytesIn),
+		BytesOut:        atomic.LoadUint64(&p.bytesOut),
+		InFlight:        inFlight,
+		Latency1m:       time.Duration(int64(p.latency1m.Mean())),
+		Latency5m:       time.Duration(int64(p.latency5m.Mean())),
+		Latency15m:      time.Duration(int64(p.latency15m.Mean())),
+	}
+}
+
+// Allow reports whether a new outbound request to this peer should
+// be dispatched, applying leaky-bucket back-pressure so that a
+// single slow or unresponsive peer cannot monopolize retrieval
+// traffic. The protocol handler should call this before every
+// outbound RetrieveRequest and skip the peer if it returns false.
+func (p *Peer) Allow() bool {
+	if p.limiter.allow() {
+		return true
+	}
+	metricsRequestsThrottled.Inc(1)
+	return false
+}
+
+const (
+	// peerRequestRate is the steady-state number of requests per
+	// second a single peer is allowed to sustain.
+	peerRequestRate = 10
+	// peerRequestBurst is the number of requests a peer may send in
+	// a burst before the leaky-bucket rate limiter starts throttling.
+	peerRequestBurst = 20
+)
+
+// leakyBucket is a leaky-bucket rate limiter: capacity tokens are
+// available immediately, refilling continuously at rate tokens per
+// second up to capacity.
+type leakyBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// newLeakyBucket returns a leakyBucket that refills at rate tokens
+// per second, holding at most capacity tokens.
+func newLeakyBucket(rate, capacity float64) *leakyBucket {
+	return &leakyBucket{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     now(),
+	}
+}
+
+// allow reports whether a token is available, consuming it if so.
+func (b *leakyBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := now()
+	b.tokens += t.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = t
+
+	if b.tokens < 1 {
+		return false
 	}
+	b.tokens--
+	return true
 }
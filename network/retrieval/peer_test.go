@@ -0,0 +1,200 @@
+// Copyright 2019 The Swarm Authors
+// This file is part of the Swarm library.
+//
+// The Swarm library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The Swarm library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the Swarm library. If not, see <http://www.gnu.org/licenses/>.
+
+package retrieval
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// newTestPeer builds a Peer with its tracking state initialized but
+// no embedded BzzPeer, since the accounting exercised below never
+// touches it. A real Peer is always constructed through NewPeer with
+// a connected BzzPeer.
+func newTestPeer() *Peer {
+	latency1m, latency5m, latency15m := newLatencyHistograms()
+	return &Peer{
+		logger:     log.New("peer", "test"),
+		inflight:   make(map[string]inflightRequest),
+		latency1m:  latency1m,
+		latency5m:  latency5m,
+		latency15m: latency15m,
+		limiter:    newLeakyBucket(peerRequestRate, peerRequestBurst),
+	}
+}
+
+// TestPeer_TrackRequestTimeout validates that a request whose done
+// function is never called is evicted once requestTimeout has
+// elapsed, and counted as a timeout rather than left in-flight
+// forever.
+func TestPeer_TrackRequestTimeout(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	p := newTestPeer()
+	addr := []byte("chunk-address")
+
+	p.TrackRequest(addr)
+
+	if stats := p.Stats(); stats.InFlight != 1 {
+		t.Fatalf("got %v in-flight requests, want 1", stats.InFlight)
+	}
+
+	now = func() time.Time { return start.Add(requestTimeout + time.Second) }
+
+	// any TrackRequest call sweeps expired entries as a side effect
+	done := p.TrackRequest([]byte("other-address"))
+	done(nil)
+
+	if stats := p.Stats(); stats.InFlight != 0 {
+		t.Fatalf("got %v in-flight requests after sweep, want 0", stats.InFlight)
+	}
+}
+
+// TestPeer_TrackRequestDedup validates that issuing a second request
+// for the same address while the first is still in flight does not
+// panic or corrupt accounting, and that completing one of them
+// resolves only its own entry.
+func TestPeer_TrackRequestDedup(t *testing.T) {
+	p := newTestPeer()
+	addr := []byte("chunk-address")
+
+	done1 := p.TrackRequest(addr)
+	if stats := p.Stats(); stats.InFlight != 1 {
+		t.Fatalf("got %v in-flight requests, want 1", stats.InFlight)
+	}
+
+	done2 := p.TrackRequest(addr)
+	if stats := p.Stats(); stats.InFlight != 1 {
+		t.Fatalf("got %v in-flight requests after duplicate, want 1", stats.InFlight)
+	}
+
+	done2(nil)
+	if stats := p.Stats(); stats.InFlight != 0 {
+		t.Fatalf("got %v in-flight requests after done2, want 0", stats.InFlight)
+	}
+
+	// the first done call resolves an address no longer tracked; it
+	// must be a no-op rather than removing done2's newer entry or
+	// double-counting delivery
+	done1(nil)
+	if stats := p.Stats(); stats.ChunksDelivered != 1 {
+		t.Fatalf("got %v chunks delivered, want 1", stats.ChunksDelivered)
+	}
+}
+
+// TestPeer_TrackRequestConcurrent validates that concurrent
+// TrackRequest/done calls across many addresses do not race and
+// leave Stats consistent.
+func TestPeer_TrackRequestConcurrent(t *testing.T) {
+	p := newTestPeer()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			addr := []byte(fmt.Sprintf("addr-%d", i))
+			done := p.TrackRequest(addr)
+			done(nil)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := p.Stats()
+	if stats.InFlight != 0 {
+		t.Errorf("got %v in-flight requests, want 0", stats.InFlight)
+	}
+	if stats.ChunksRequested != 50 {
+		t.Errorf("got %v chunks requested, want 50", stats.ChunksRequested)
+	}
+	if stats.ChunksDelivered != 50 {
+		t.Errorf("got %v chunks delivered, want 50", stats.ChunksDelivered)
+	}
+}
+
+// TestPeer_TrackRequestLatency validates that completed requests feed
+// this peer's own rolling latency histograms, rather than only the
+// package-level shared one, and that a fresh peer with no completed
+// requests reports zero latency.
+func TestPeer_TrackRequestLatency(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	p := newTestPeer()
+
+	if stats := p.Stats(); stats.Latency1m != 0 || stats.Latency5m != 0 || stats.Latency15m != 0 {
+		t.Fatalf("got non-zero latency before any request completed: %+v", stats)
+	}
+
+	done := p.TrackRequest([]byte("chunk-address"))
+	now = func() time.Time { return start.Add(100 * time.Millisecond) }
+	done(nil)
+
+	stats := p.Stats()
+	if stats.Latency1m != 100*time.Millisecond {
+		t.Errorf("got 1m latency %v, want %v", stats.Latency1m, 100*time.Millisecond)
+	}
+	if stats.Latency5m != 100*time.Millisecond {
+		t.Errorf("got 5m latency %v, want %v", stats.Latency5m, 100*time.Millisecond)
+	}
+	if stats.Latency15m != 100*time.Millisecond {
+		t.Errorf("got 15m latency %v, want %v", stats.Latency15m, 100*time.Millisecond)
+	}
+}
+
+// TestPeer_AllowRateLimit validates that Allow grants the configured
+// burst of requests immediately and then throttles further requests
+// until the bucket refills.
+func TestPeer_AllowRateLimit(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+
+	start := time.Now()
+	now = func() time.Time { return start }
+
+	p := newTestPeer()
+
+	for i := 0; i < peerRequestBurst; i++ {
+		if !p.Allow() {
+			t.Fatalf("request %v within burst was throttled", i)
+		}
+	}
+
+	if p.Allow() {
+		t.Fatal("request beyond burst was not throttled")
+	}
+
+	// after a second, the bucket has refilled by peerRequestRate tokens
+	now = func() time.Time { return start.Add(time.Second) }
+
+	for i := 0; i < peerRequestRate; i++ {
+		if !p.Allow() {
+			t.Fatalf("refilled request %v was throttled", i)
+		}
+	}
+	if p.Allow() {
+		t.Fatal("request beyond refilled capacity was not throttled")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chunk defines the content-addressed chunk type shared by
+// Swarm's storage layers, independent of any particular storage
+// backend.
+package chunk
+
+import (
+	"encoding/hex"
+)
+
+// AddressLength is the length of a chunk address in bytes.
+const AddressLength = 32
+
+// Address is an alias for a byte slice that represents
+// the address of a chunk.
+type Address []byte
+
+// Hex returns a hex string representation of the address.
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a)
+}
+
+// String returns a truncated hex string representation of the address,
+// used for logging.
+func (a Address) String() string {
+	if len(a) == 0 {
+		return ""
+	}
+	s := a.Hex()
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
+
+// Chunk represents a content-addressed piece of data together
+// with its address and any attached accessors.
+type Chunk interface {
+	Address() Address
+	Data() []byte
+}
+
+type chunk struct {
+	addr  Address
+	sdata []byte
+}
+
+// NewChunk creates a new chunk from its address and data.
+func NewChunk(addr Address, data []byte) Chunk {
+	return &chunk{
+		addr:  addr,
+		sdata: data,
+	}
+}
+
+func (c *chunk) Address() Address {
+	return c.addr
+}
+
+func (c *chunk) Data() []byte {
+	return c.sdata
+}
+
+// Proximity returns the proximity order of the MSB distance between
+// two addresses x and y. The order is the bit position of the first
+// differing bit, counting from the most significant bit, capped at
+// the number of bits in the address.
+func Proximity(one, other []byte) (ret int) {
+	b := (len(one)*8 - 1)
+	if b > len(other)*8-1 {
+		b = len(other)*8 - 1
+	}
+	for i := 0; i <= b; i++ {
+		oxo := one[i/8] ^ other[i/8]
+		if (oxo>>uint8(7-i%8))&0x01 != 0 {
+			return i
+		}
+	}
+	return b + 1
+}
+
+// Validator validates chunk data against its address before it is
+// allowed to be stored, so that content-addressed and single-owner
+// chunks can be accepted side by side by the same Putter.
+type Validator interface {
+	Validate(addr Address, data []byte) bool
+}
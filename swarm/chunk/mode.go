@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package chunk
+
+// ModeGet enumerates the possible modes for retrieving a chunk,
+// used by a storage layer's Getter.
+type ModeGet int
+
+const (
+	// ModeGetRequest is used when a chunk is retrieved in response to
+	// a retrieval request, bumping it in any LRU used for eviction.
+	ModeGetRequest ModeGet = iota
+	// ModeGetLookup is used for a plain retrieval that has no side
+	// effect on the chunk's eviction state.
+	ModeGetLookup
+)
+
+func (m ModeGet) String() string {
+	switch m {
+	case ModeGetRequest:
+		return "Request"
+	case ModeGetLookup:
+		return "Lookup"
+	default:
+		return "Unknown"
+	}
+}
+
+// ModePut enumerates the possible modes for storing a new chunk,
+// used by a storage layer's Putter.
+type ModePut int
+
+const (
+	// ModePutRequest is used when a chunk is put as a result of a
+	// retrieval request and delivery.
+	ModePutRequest ModePut = iota
+	// ModePutSync is used when a chunk is put by the syncer, a chunk
+	// that is coming from another node.
+	ModePutSync
+	// ModePutUpload is used when a chunk is put locally by the user,
+	// e.g. uploading a file.
+	ModePutUpload
+	// ModePutUploadPin is used when a chunk is put locally by the
+	// user and pinned in the same operation, storing it first if it
+	// is not yet present.
+	ModePutUploadPin
+)
+
+func (m ModePut) String() string {
+	switch m {
+	case ModePutRequest:
+		return "Request"
+	case ModePutSync:
+		return "Sync"
+	case ModePutUpload:
+		return "Upload"
+	case ModePutUploadPin:
+		return "UploadPin"
+	default:
+		return "Unknown"
+	}
+}
+
+// ModeSet enumerates the possible modes for updating the state of a
+// chunk that has already been stored, identified only by its
+// address.
+type ModeSet int
+
+const (
+	// ModeSetSync is used after a synced chunk is confirmed to be
+	// successfully stored by its neighbourhood.
+	ModeSetSync ModeSet = iota
+	// ModeSetAccess is used to bump a chunk's position in the gc
+	// index without retrieving its data.
+	ModeSetAccess
+	// ModeSetPin is used to increment a chunk's pin counter,
+	// excluding it from garbage collection for as long as the
+	// counter is greater than zero.
+	ModeSetPin
+	// ModeSetUnpin is used to decrement a chunk's pin counter, making
+	// it eligible for garbage collection again once the counter
+	// reaches zero.
+	ModeSetUnpin
+	// ModeSetRemove is used to remove a chunk from all indexes,
+	// typically triggered by the garbage collector.
+	ModeSetRemove
+)
+
+func (m ModeSet) String() string {
+	switch m {
+	case ModeSetSync:
+		return "Sync"
+	case ModeSetAccess:
+		return "Access"
+	case ModeSetPin:
+		return "Pin"
+	case ModeSetUnpin:
+		return "Unpin"
+	case ModeSetRemove:
+		return "Remove"
+	default:
+		return "Unknown"
+	}
+}
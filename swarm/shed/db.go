@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package shed provides a simple abstraction components to compose
+// more complex operations on storage data organized in fields and indexes.
+//
+// Schema example:
+
+// DB implements a schema.
+package shed
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// DB provides abstractions on top of LevelDB in order to
+// implement complex structures using fields and indexes.
+// It provides a schema functionality to store fields and indexes
+// information about naming and types.
+type DB struct {
+	ldb *leveldb.DB
+
+	schemaMu sync.Mutex // protects fields and indices
+}
+
+// NewDB constructs a new DB and validates the schema
+// if it exists in the database on the given path.
+func NewDB(path string) (db *DB, err error) {
+	ldb, err := leveldb.OpenFile(path, &opt.Options{})
+	if err != nil {
+		return nil, err
+	}
+	db = &DB{
+		ldb: ldb,
+	}
+	return db, nil
+}
+
+// Put wraps LevelDB Put method to increment metrics counter.
+func (db *DB) Put(key []byte, value []byte) (err error) {
+	return db.ldb.Put(key, value, nil)
+}
+
+// Get wraps LevelDB Get method to increment metrics counter.
+func (db *DB) Get(key []byte) (value []byte, err error) {
+	return db.ldb.Get(key, nil)
+}
+
+// Has wraps LevelDB Has method to increment metrics counter.
+func (db *DB) Has(key []byte) (yes bool, err error) {
+	return db.ldb.Has(key, nil)
+}
+
+// Delete wraps LevelDB Delete method to increment metrics counter.
+func (db *DB) Delete(key []byte) (err error) {
+	return db.ldb.Delete(key, nil)
+}
+
+// NewIterator wraps LevelDB NewIterator method to increment metrics counter.
+func (db *DB) NewIterator() iterator.Iterator {
+	return db.ldb.NewIterator(nil, nil)
+}
+
+// WriteBatch wraps LevelDB Write method to increment metrics counter.
+func (db *DB) WriteBatch(batch *leveldb.Batch) (err error) {
+	return db.ldb.Write(batch, nil)
+}
+
+// Close closes LevelDB database.
+func (db *DB) Close() (err error) {
+	return db.ldb.Close()
+}
+
+// schemaIndexPrefix returns a unique, monotonically increasing byte
+// prefix for the requested index name, persisting the name/prefix
+// assignment in the schema so that it is stable across restarts.
+func (db *DB) schemaIndexPrefix(name string) (id byte, err error) {
+	db.schemaMu.Lock()
+	defer db.schemaMu.Unlock()
+
+	key := append([]byte{schemaKeyPrefix}, []byte(name)...)
+	v, err := db.Get(key)
+	if err == nil {
+		if len(v) != 1 {
+			return 0, fmt.Errorf("invalid schema entry for index %q", name)
+		}
+		return v[0], nil
+	}
+	if err != leveldb.ErrNotFound {
+		return 0, err
+	}
+
+	next, err := db.Get([]byte{schemaNextIDKey})
+	var id16 byte
+	if err == nil {
+		id16 = next[0]
+	} else if err == leveldb.ErrNotFound {
+		id16 = schemaIndexIDStart
+	} else {
+		return 0, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put(key, []byte{id16})
+	batch.Put([]byte{schemaNextIDKey}, []byte{id16 + 1})
+	if err := db.WriteBatch(batch); err != nil {
+		return 0, err
+	}
+	return id16, nil
+}
+
+const (
+	schemaKeyPrefix    = 0
+	schemaNextIDKey    = 1
+	schemaIndexIDStart = 2
+)
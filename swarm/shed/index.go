@@ -0,0 +1,295 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package shed
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// IndexItem holds fields relevant to Index functions.
+// Every field is persisted in the LevelDB only if it is
+// part of the index encode/decode functions. IndexItem
+// with unexported fields are used only as function
+// arguments.
+type IndexItem struct {
+	Address         []byte
+	Data            []byte
+	StoreTimestamp  int64
+	AccessTimestamp int64
+	// BinID is a monotonic identifier assigned to an
+	// item within its proximity order bin, used to give
+	// pull iterations a stable, gap-free cursor.
+	BinID uint64
+	// PO is the proximity order bin an item belongs to.
+	PO uint8
+	// PinCounter is the number of times an address has
+	// been pinned. It is non-zero only in indexes that
+	// track pinning state.
+	PinCounter uint64
+	// BatchID is the postage batch an address is stamped
+	// with. It is non-zero only in indexes that track
+	// postage stamp state.
+	BatchID []byte
+	// StampIndex is the index of a chunk's stamp within its
+	// batch, used to order chunks of the same batch and bin.
+	StampIndex uint64
+	// Depth is the neighbourhood depth of the postage batch
+	// at the time its stamp was last set.
+	Depth uint8
+	// Value is the value assigned to a postage batch. It is
+	// non-zero only in indexes that rank batches against each
+	// other.
+	Value uint64
+}
+
+// IndexIterFunc is a callback on every IndexItem that is decoded
+// by iterating on an Index keys. By returning a true for stop
+// variable, iteration will stop, and by returning the error, that
+// error will be propagated to the called iterator method on Index.
+type IndexIterFunc func(item IndexItem) (stop bool, err error)
+
+// IndexFuncs structure defines functions for encoding and decoding
+// LevelDB keys and values for an Index.
+type IndexFuncs struct {
+	EncodeKey   func(fields IndexItem) (key []byte, err error)
+	DecodeKey   func(key []byte) (e IndexItem, err error)
+	EncodeValue func(fields IndexItem) (value []byte, err error)
+	DecodeValue func(keyFields IndexItem, value []byte) (e IndexItem, err error)
+}
+
+// Index represents a set of LevelDB key/value pairs that have common
+// prefix. It holds functions for encoding and decoding keys and values
+// to provide transparent actions on saved data which inclide: Get, Put,
+// Delete and Iterate.
+type Index struct {
+	db              *DB
+	prefix          []byte
+	encodeKeyFunc   func(fields IndexItem) (key []byte, err error)
+	decodeKeyFunc   func(key []byte) (e IndexItem, err error)
+	encodeValueFunc func(fields IndexItem) (value []byte, err error)
+	decodeValueFunc func(keyFields IndexItem, value []byte) (e IndexItem, err error)
+}
+
+// NewIndex returns a new Index instance with defined name and
+// encoding functions. The name must be unique and will be validated
+// on database schema for a key prefix byte.
+func (db *DB) NewIndex(name string, funcs IndexFuncs) (f Index, err error) {
+	id, err := db.schemaIndexPrefix(name)
+	if err != nil {
+		return f, err
+	}
+	prefix := []byte{id}
+	return Index{
+		db:     db,
+		prefix: prefix,
+		// encode/decode functions are wrapping the provided functions
+		// to add the index prefix to keys
+		encodeKeyFunc: func(fields IndexItem) (key []byte, err error) {
+			key, err = funcs.EncodeKey(fields)
+			if err != nil {
+				return nil, err
+			}
+			return append(append(make([]byte, 0, len(prefix)+len(key)), prefix...), key...), nil
+		},
+		decodeKeyFunc: func(key []byte) (e IndexItem, err error) {
+			return funcs.DecodeKey(key[len(prefix):])
+		},
+		encodeValueFunc: funcs.EncodeValue,
+		decodeValueFunc: funcs.DecodeValue,
+	}, nil
+}
+
+// Get accepts key fields represented as IndexItem to retrieve a
+// value from the index and return maximum available information
+// from the index represented as another IndexItem.
+func (f Index) Get(keyFields IndexItem) (out IndexItem, err error) {
+	key, err := f.encodeKeyFunc(keyFields)
+	if err != nil {
+		return out, err
+	}
+	value, err := f.db.Get(key)
+	if err != nil {
+		return out, err
+	}
+	keyItem, err := f.decodeKeyFunc(key)
+	if err != nil {
+		return out, err
+	}
+	out, err = f.decodeValueFunc(keyItem, value)
+	if err != nil {
+		return out, err
+	}
+	return mergeIndexItem(keyItem, out), nil
+}
+
+// mergeIndexItem fills zero-value fields of value with the
+// corresponding fields decoded from the key, as key fields are not
+// duplicated in the stored value.
+func mergeIndexItem(key, value IndexItem) (out IndexItem) {
+	out = value
+	if out.Address == nil {
+		out.Address = key.Address
+	}
+	out.PO = key.PO
+	if out.StoreTimestamp == 0 {
+		out.StoreTimestamp = key.StoreTimestamp
+	}
+	if out.AccessTimestamp == 0 {
+		out.AccessTimestamp = key.AccessTimestamp
+	}
+	if out.BinID == 0 {
+		out.BinID = key.BinID
+	}
+	if out.BatchID == nil {
+		out.BatchID = key.BatchID
+	}
+	if out.Depth == 0 {
+		out.Depth = key.Depth
+	}
+	return out
+}
+
+// Put accepts IndexItem to encode information from it
+// and save it to the database.
+func (f Index) Put(i IndexItem) (err error) {
+	key, err := f.encodeKeyFunc(i)
+	if err != nil {
+		return err
+	}
+	value, err := f.encodeValueFunc(i)
+	if err != nil {
+		return err
+	}
+	return f.db.Put(key, value)
+}
+
+// PutInBatch is the same as Put method, but it just
+// saves the key/value pair to the batch instead
+// directly to the database.
+func (f Index) PutInBatch(batch *leveldb.Batch, i IndexItem) (err error) {
+	key, err := f.encodeKeyFunc(i)
+	if err != nil {
+		return err
+	}
+	value, err := f.encodeValueFunc(i)
+	if err != nil {
+		return err
+	}
+	batch.Put(key, value)
+	return nil
+}
+
+// Delete accepts IndexItem to remove a key/value pair
+// from the database based on its fields.
+func (f Index) Delete(keyFields IndexItem) (err error) {
+	key, err := f.encodeKeyFunc(keyFields)
+	if err != nil {
+		return err
+	}
+	return f.db.Delete(key)
+}
+
+// DeleteInBatch is the same as Delete just the operation
+// is performed on the batch instead on the database.
+func (f Index) DeleteInBatch(batch *leveldb.Batch, keyFields IndexItem) (err error) {
+	key, err := f.encodeKeyFunc(keyFields)
+	if err != nil {
+		return err
+	}
+	batch.Delete(key)
+	return nil
+}
+
+// IterateOptions defines optional parameters for Iterate function.
+type IterateOptions struct {
+	// StartFrom is the Item to start the iteration from.
+	StartFrom *IndexItem
+	// SkipStartFromItem set to true will skip StartFrom Item
+	// in iteration.
+	SkipStartFromItem bool
+	// Prefix defines the iteration range as the prefix.
+	Prefix []byte
+}
+
+// Iterate function iterates over keys of the Index.
+// If IterateOptions is nil, the iteration is over all keys.
+func (f Index) Iterate(fn IndexIterFunc, options *IterateOptions) (err error) {
+	if options == nil {
+		options = new(IterateOptions)
+	}
+	prefix := append(f.prefix, options.Prefix...)
+	var startKey []byte
+	if options.StartFrom != nil {
+		startKey, err = f.encodeKeyFunc(*options.StartFrom)
+		if err != nil {
+			return err
+		}
+	}
+	it := f.db.NewIterator()
+	defer it.Release()
+
+	var start []byte
+	if startKey != nil {
+		start = startKey
+	} else {
+		start = prefix
+	}
+	for ok := it.Seek(start); ok; ok = it.Next() {
+		key := it.Key()
+		if len(key) < len(prefix) || string(key[:len(prefix)]) != string(prefix) {
+			break
+		}
+		if options.SkipStartFromItem && startKey != nil && string(key) == string(startKey) {
+			continue
+		}
+		// Key and Value byte slices returned by the iterator are only
+		// valid until the next call to Next, but decoded IndexItems
+		// may retain references into them (e.g. Address), so they
+		// must be copied before decoding.
+		keyIndexItem, err := f.decodeKeyFunc(append([]byte(nil), key...))
+		if err != nil {
+			return err
+		}
+		valueIndexItem, err := f.decodeValueFunc(keyIndexItem, append([]byte(nil), it.Value()...))
+		if err != nil {
+			return err
+		}
+		stop, err := fn(mergeIndexItem(keyIndexItem, valueIndexItem))
+		if err != nil {
+			return err
+		}
+		if stop {
+			break
+		}
+	}
+	return it.Error()
+}
+
+// IterateAll iterates over all keys of the Index.
+func (f Index) IterateAll(fn IndexIterFunc) (err error) {
+	return f.Iterate(fn, nil)
+}
+
+// Has checks whether a key described by the provided fields
+// is present in the index.
+func (f Index) Has(keyFields IndexItem) (bool, error) {
+	key, err := f.encodeKeyFunc(keyFields)
+	if err != nil {
+		return false, err
+	}
+	return f.db.Has(key)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// nextBinID returns a new monotonically increasing BinID for the
+// given proximity order bin, persisting the updated counter.
+func (db *DB) nextBinID(po uint8) (id uint64, err error) {
+	item, err := db.binIDCounter.Get(shed.IndexItem{PO: po})
+	if err != nil {
+		if err != leveldb.ErrNotFound {
+			return 0, err
+		}
+		item = shed.IndexItem{PO: po}
+	}
+	id = item.BinID + 1
+	item.BinID = id
+	if err := db.binIDCounter.Put(item); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// subscribePullTrigger registers a new trigger channel for the given
+// bin, returning it together with a function that removes it again.
+// Triggers are fed by putSyncing/putUpload whenever a new chunk is
+// stored in that bin.
+func (db *DB) subscribePullTrigger(bin uint8) (c chan struct{}, unsubscribe func()) {
+	c = make(chan struct{}, 1)
+
+	db.pullTriggersMu.Lock()
+	db.pullTriggers[bin] = append(db.pullTriggers[bin], c)
+	db.pullTriggersMu.Unlock()
+
+	return c, func() {
+		db.pullTriggersMu.Lock()
+		defer db.pullTriggersMu.Unlock()
+
+		triggers := db.pullTriggers[bin]
+		for i, t := range triggers {
+			if t == c {
+				db.pullTriggers[bin] = append(triggers[:i], triggers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// triggerPullSubscriptions notifies all registered trigger channels
+// for the given bin that a new chunk has been stored. Sends are
+// non-blocking, as the trigger only needs to carry the "there may be
+// new data" signal, not every individual Put.
+func (db *DB) triggerPullSubscriptions(bin uint8) {
+	db.pullTriggersMu.Lock()
+	defer db.pullTriggersMu.Unlock()
+
+	for _, c := range db.pullTriggers[bin] {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,499 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package localstore provides disk storage layer for Swarm chunk data,
+// bundling a number of LevelDB indexes into a single DB object that
+// implements a set of access Modes for the various stages a chunk goes
+// through: syncing, uploading, requesting and garbage collection.
+package localstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// now returns the current unix nanosecond timestamp.
+// It is a variable so that it can be overridden in tests.
+var now = func() int64 {
+	return time.Now().UTC().UnixNano()
+}
+
+// Options define optional parameters for configuring DB.
+type Options struct {
+	// UseRetrievalCompositeIndex instructs DB to use a single
+	// index that holds chunk data and access timestamp together,
+	// instead of two separate indexes.
+	UseRetrievalCompositeIndex bool
+	// ReserveCapacity is the maximum number of chunks the postage
+	// reserve may hold. Once SetPostageStamp or UnreserveBatch grows
+	// the reserve past this limit, chunks are unreserved from the
+	// lowest-value batch's outermost bin first to bring it back
+	// within capacity. Zero means the reserve is unbounded.
+	ReserveCapacity uint64
+}
+
+// DB is the main storage structure that is backed by a number of
+// LevelDB indexes, organized with the shed package.
+type DB struct {
+	shed *shed.DB
+
+	baseKey []byte
+
+	useRetrievalCompositeIndex bool
+
+	// retrievalCompositeIndex stores chunk data and access timestamp,
+	// used when UseRetrievalCompositeIndex option is set
+	retrievalCompositeIndex shed.Index
+	// retrievalDataIndex stores chunk data keyed by address,
+	// used when UseRetrievalCompositeIndex option is not set
+	retrievalDataIndex shed.Index
+	// retrievalAccessIndex stores the access timestamp keyed by address,
+	// used together with retrievalDataIndex
+	retrievalAccessIndex shed.Index
+	// pullIndex contains all chunks ordered by their proximity order
+	// to baseKey, then by the order they entered the node, used by the
+	// syncer to pull chunks for a given bin
+	pullIndex shed.Index
+	// binIDCounter stores, per proximity order bin, the last BinID
+	// assigned to a chunk stored in that bin
+	binIDCounter shed.Index
+	// pullTriggers holds one notification channel per proximity
+	// order bin, used to wake up blocked SubscribePull iterators
+	// when new chunks are stored
+	pullTriggers   map[uint8][]chan struct{}
+	pullTriggersMu sync.Mutex
+
+	// pushTriggers holds notification channels used to wake up
+	// blocked SubscribePush iterators when new chunks are pushed
+	pushTriggers   []chan struct{}
+	pushTriggersMu sync.Mutex
+	// pushIndex contains all chunks that need to be pushed to their
+	// neighbourhoods, removed once their delivery has been confirmed
+	pushIndex shed.Index
+	// gcIndex contains all chunks that are candidates for eviction,
+	// ordered by their access timestamp
+	gcIndex shed.Index
+	// pinIndex stores a reference counter per pinned chunk address,
+	// used to keep a chunk out of gcIndex while it is pinned
+	pinIndex shed.Index
+
+	// postageIndex stores the postage stamp (BatchID, StampIndex and
+	// the batch's neighbourhood Depth at the time it was set) for a
+	// chunk address, used to decide reserve membership
+	postageIndex shed.Index
+	// postageChunksIndex indexes stamped chunk addresses by
+	// BatchID|PO|StampIndex, so that all chunks of a batch can be
+	// walked in proximity order when the batch is unreserved
+	postageChunksIndex shed.Index
+	// batchValueIndex stores the value assigned to a postage batch
+	// keyed by BatchID, used to look up and replace a batch's value
+	// when it changes
+	batchValueIndex shed.Index
+	// valueBatchIndex indexes batches by Value|BatchID, so that the
+	// lowest-value batch can be found without scanning every batch
+	valueBatchIndex shed.Index
+	// reserveCapacity is the configured maximum size of the postage
+	// reserve, copied from Options. Zero means unbounded.
+	reserveCapacity uint64
+
+	// sizeCounter stores the number of chunks in the retrieval index
+	sizeCounter shed.Uint64Field
+	// reserveSizeCounter stores the number of chunks currently held
+	// in the postage reserve, counted separately from sizeCounter
+	reserveSizeCounter shed.Uint64Field
+}
+
+// New returns a new DB. All fields and indexes are initialized
+// and possibly migrated from a previous schema.
+func New(path string, baseKey []byte, o *Options) (db *DB, err error) {
+	if o == nil {
+		o = new(Options)
+	}
+	db = &DB{
+		baseKey:                    baseKey,
+		useRetrievalCompositeIndex: o.UseRetrievalCompositeIndex,
+		reserveCapacity:            o.ReserveCapacity,
+		pullTriggers:               make(map[uint8][]chan struct{}),
+	}
+	db.shed, err = shed.NewDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.useRetrievalCompositeIndex {
+		db.retrievalCompositeIndex, err = db.shed.NewIndex("Address->StoreTimestamp|AccessTimestamp|Data", shed.IndexFuncs{
+			EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+				return fields.Address, nil
+			},
+			DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+				e.Address = key
+				return e, nil
+			},
+			EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+				b := make([]byte, 24)
+				encodeInt64(b[:8], fields.StoreTimestamp)
+				encodeInt64(b[8:16], fields.AccessTimestamp)
+				encodeBinID(b[16:24], fields.BinID)
+				value = append(b, fields.Data...)
+				return value, nil
+			},
+			DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+				e.StoreTimestamp = decodeInt64(value[:8])
+				e.AccessTimestamp = decodeInt64(value[8:16])
+				e.BinID = decodeBinID(value[16:24])
+				e.Data = value[24:]
+				return e, nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		db.retrievalDataIndex, err = db.shed.NewIndex("Address->StoreTimestamp|Data", shed.IndexFuncs{
+			EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+				return fields.Address, nil
+			},
+			DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+				e.Address = key
+				return e, nil
+			},
+			EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+				b := make([]byte, 16)
+				encodeInt64(b[:8], fields.StoreTimestamp)
+				encodeBinID(b[8:16], fields.BinID)
+				value = append(b, fields.Data...)
+				return value, nil
+			},
+			DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+				e.StoreTimestamp = decodeInt64(value[:8])
+				e.BinID = decodeBinID(value[8:16])
+				e.Data = value[16:]
+				return e, nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		db.retrievalAccessIndex, err = db.shed.NewIndex("Address->AccessTimestamp", shed.IndexFuncs{
+			EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+				return fields.Address, nil
+			},
+			DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+				e.Address = key
+				return e, nil
+			},
+			EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+				b := make([]byte, 8)
+				encodeInt64(b, fields.AccessTimestamp)
+				return b, nil
+			},
+			DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+				e.AccessTimestamp = decodeInt64(value)
+				return e, nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db.pullIndex, err = db.shed.NewIndex("PO|BinID|Hash->StoreTimestamp", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			key = make([]byte, 41)
+			key[0] = fields.PO
+			encodeBinID(key[1:9], fields.BinID)
+			copy(key[9:], fields.Address)
+			return key, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.PO = key[0]
+			e.BinID = decodeBinID(key[1:9])
+			e.Address = key[9:]
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			b := make([]byte, 8)
+			encodeInt64(b, fields.StoreTimestamp)
+			return b, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.StoreTimestamp = decodeInt64(value)
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.binIDCounter, err = db.shed.NewIndex("PO->BinID", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			return []byte{byte(fields.PO)}, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.PO = uint8(key[0])
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			b := make([]byte, 8)
+			encodeBinID(b, fields.BinID)
+			return b, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.BinID = decodeBinID(value)
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.pushIndex, err = db.shed.NewIndex("StoreTimestamp|Hash->nil", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			key = make([]byte, 40)
+			encodeInt64(key[:8], fields.StoreTimestamp)
+			copy(key[8:], fields.Address)
+			return key, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.StoreTimestamp = decodeInt64(key[:8])
+			e.Address = key[8:]
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			return nil, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.gcIndex, err = db.shed.NewIndex("AccessTimestamp|StoreTimestamp|Hash->nil", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			key = make([]byte, 48)
+			encodeInt64(key[:8], fields.AccessTimestamp)
+			encodeInt64(key[8:16], fields.StoreTimestamp)
+			copy(key[16:], fields.Address)
+			return key, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.AccessTimestamp = decodeInt64(key[:8])
+			e.StoreTimestamp = decodeInt64(key[8:16])
+			e.Address = key[16:]
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			return nil, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.pinIndex, err = db.shed.NewIndex("Address->PinCounter", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			return fields.Address, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.Address = key
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			b := make([]byte, 8)
+			encodeInt64(b, int64(fields.PinCounter))
+			return b, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.PinCounter = uint64(decodeInt64(value))
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.postageIndex, err = db.shed.NewIndex("Address->BatchID|StampIndex|Depth", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			return fields.Address, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.Address = key
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			b := make([]byte, batchIDLength+9)
+			copy(b[:batchIDLength], fields.BatchID)
+			encodeBinID(b[batchIDLength:batchIDLength+8], fields.StampIndex)
+			b[batchIDLength+8] = fields.Depth
+			return b, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.BatchID = append([]byte(nil), value[:batchIDLength]...)
+			e.StampIndex = decodeBinID(value[batchIDLength : batchIDLength+8])
+			e.Depth = value[batchIDLength+8]
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.postageChunksIndex, err = db.shed.NewIndex("BatchID|PO|StampIndex->Address", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			key = make([]byte, batchIDLength+9)
+			copy(key[:batchIDLength], fields.BatchID)
+			key[batchIDLength] = fields.PO
+			encodeBinID(key[batchIDLength+1:], fields.StampIndex)
+			return key, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.BatchID = append([]byte(nil), key[:batchIDLength]...)
+			e.PO = key[batchIDLength]
+			e.StampIndex = decodeBinID(key[batchIDLength+1:])
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			return fields.Address, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.Address = append([]byte(nil), value...)
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.batchValueIndex, err = db.shed.NewIndex("BatchID->Value", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			return fields.BatchID, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.BatchID = key
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			b := make([]byte, 8)
+			encodeBinID(b, fields.Value)
+			return b, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			e.Value = decodeBinID(value)
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.valueBatchIndex, err = db.shed.NewIndex("Value|BatchID->", shed.IndexFuncs{
+		EncodeKey: func(fields shed.IndexItem) (key []byte, err error) {
+			key = make([]byte, 8+batchIDLength)
+			encodeBinID(key[:8], fields.Value)
+			copy(key[8:], fields.BatchID)
+			return key, nil
+		},
+		DecodeKey: func(key []byte) (e shed.IndexItem, err error) {
+			e.Value = decodeBinID(key[:8])
+			e.BatchID = append([]byte(nil), key[8:]...)
+			return e, nil
+		},
+		EncodeValue: func(fields shed.IndexItem) (value []byte, err error) {
+			return nil, nil
+		},
+		DecodeValue: func(keyItem shed.IndexItem, value []byte) (e shed.IndexItem, err error) {
+			return e, nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.sizeCounter, err = db.shed.NewUint64Field("size")
+	if err != nil {
+		return nil, err
+	}
+
+	db.reserveSizeCounter, err = db.shed.NewUint64Field("reservesize")
+	if err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Close closes the underlying LevelDB database.
+func (db *DB) Close() (err error) {
+	return db.shed.Close()
+}
+
+// addressToItem is a helper to create an IndexItem with only
+// the Address field set, used as a key for Get/Delete operations.
+func addressToItem(addr chunk.Address) shed.IndexItem {
+	return shed.IndexItem{
+		Address: addr,
+	}
+}
+
+// gcCandidateItem builds the gcIndex entry for a chunk that is
+// becoming a regular LRU candidate, falling back to StoreTimestamp
+// when the chunk has never been accessed, so that every path
+// re-inserting the same never-accessed chunk agrees on its key.
+func gcCandidateItem(storeItem shed.IndexItem) shed.IndexItem {
+	accessTimestamp := storeItem.AccessTimestamp
+	if accessTimestamp == 0 {
+		accessTimestamp = storeItem.StoreTimestamp
+	}
+	return shed.IndexItem{
+		Address:         storeItem.Address,
+		StoreTimestamp:  storeItem.StoreTimestamp,
+		AccessTimestamp: accessTimestamp,
+	}
+}
+
+func encodeInt64(b []byte, v int64) {
+	for i := 0; i < 8; i++ {
+		b[i] = byte(v >> uint(56-8*i))
+	}
+}
+
+func decodeInt64(b []byte) (v int64) {
+	for i := 0; i < 8; i++ {
+		v |= int64(b[i]) << uint(56-8*i)
+	}
+	return v
+}
+
+func encodeBinID(b []byte, v uint64) {
+	for i := 0; i < 8; i++ {
+		b[i] = byte(v >> uint(56-8*i))
+	}
+}
+
+func decodeBinID(b []byte) (v uint64) {
+	for i := 0; i < 8; i++ {
+		v |= uint64(b[i]) << uint(56-8*i)
+	}
+	return v
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+)
+
+// newTestDB is a helper that creates a new DB in a temporary
+// directory, to be used by tests. The returned cleanup function
+// removes the directory and closes the database.
+func newTestDB(t *testing.T, o *Options) (db *DB, cleanupFunc func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "swarm-localstore-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeDir := func() {
+		os.RemoveAll(dir)
+	}
+	baseKey := make([]byte, 32)
+	if _, err := rand.Read(baseKey); err != nil {
+		t.Fatal(err)
+	}
+	db, err = New(dir, baseKey, o)
+	if err != nil {
+		removeDir()
+		t.Fatal(err)
+	}
+	return db, func() {
+		db.Close()
+		removeDir()
+	}
+}
+
+// generateRandomChunk returns a new chunk with random data and a
+// matching content address, to be used in tests.
+func generateRandomChunk() chunk.Chunk {
+	data := make([]byte, 4096)
+	if _, err := rand.Read(data); err != nil {
+		panic(fmt.Sprintf("generateRandomChunk: %v", err))
+	}
+	addr := make([]byte, chunk.AddressLength)
+	if _, err := rand.Read(addr); err != nil {
+		panic(fmt.Sprintf("generateRandomChunk: %v", err))
+	}
+	return chunk.NewChunk(addr, data)
+}
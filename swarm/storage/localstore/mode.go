@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"context"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// ErrInvalidMode is returned when a Getter, Putter or Set is used
+// with a mode it does not support.
+var ErrInvalidMode = errInvalidMode{}
+
+type errInvalidMode struct{}
+
+func (errInvalidMode) Error() string { return "localstore: invalid mode" }
+
+// ErrInvalidChunk is returned by a Putter's Put when the chunk's
+// Validator rejects its address/data pair.
+var ErrInvalidChunk = errInvalidChunk{}
+
+type errInvalidChunk struct{}
+
+func (errInvalidChunk) Error() string { return "localstore: invalid chunk" }
+
+// Getter retrieves chunks with the side effects defined by its
+// ModeGet.
+type Getter struct {
+	db   *DB
+	mode chunk.ModeGet
+}
+
+// NewGetter returns a new Getter bound to mode.
+func (db *DB) NewGetter(mode chunk.ModeGet) *Getter {
+	return &Getter{
+		db:   db,
+		mode: mode,
+	}
+}
+
+// Get retrieves the chunk with the provided address, bumping its
+// position in gcIndex if the Getter's mode is ModeGetRequest.
+func (g *Getter) Get(ctx context.Context, addr chunk.Address) (ch chunk.Chunk, err error) {
+	item, err := g.db.get(addr)
+	if err != nil {
+		return nil, err
+	}
+	switch g.mode {
+	case chunk.ModeGetRequest:
+		if err := g.db.updateGCItem(item); err != nil {
+			return nil, err
+		}
+	case chunk.ModeGetLookup:
+	default:
+		return nil, ErrInvalidMode
+	}
+	return chunk.NewChunk(addr, item.Data), nil
+}
+
+// Putter stores chunks with the side effects defined by its ModePut,
+// rejecting any chunk that does not pass its Validator, if one is
+// set.
+type Putter struct {
+	db        *DB
+	mode      chunk.ModePut
+	validator chunk.Validator
+}
+
+// NewPutter returns a new Putter bound to mode. A nil validator
+// accepts every chunk.
+func (db *DB) NewPutter(mode chunk.ModePut, validator chunk.Validator) *Putter {
+	return &Putter{
+		db:        db,
+		mode:      mode,
+		validator: validator,
+	}
+}
+
+// Put validates and stores the chunk in the indexes relevant for the
+// Putter's mode. No index is touched if validation fails.
+func (p *Putter) Put(ctx context.Context, ch chunk.Chunk) (err error) {
+	if p.validator != nil && !p.validator.Validate(ch.Address(), ch.Data()) {
+		return ErrInvalidChunk
+	}
+	switch p.mode {
+	case chunk.ModePutSync:
+		return p.db.putSync(ch)
+	case chunk.ModePutUpload:
+		return p.db.putUpload(ch)
+	case chunk.ModePutUploadPin:
+		return p.db.putUploadPin(ch)
+	case chunk.ModePutRequest:
+		return p.db.putRequest(ch)
+	default:
+		return ErrInvalidMode
+	}
+}
+
+// Set updates the state of already stored chunks identified by addrs,
+// applying the side effects defined by mode.
+func (db *DB) Set(ctx context.Context, mode chunk.ModeSet, addrs ...chunk.Address) (err error) {
+	for _, addr := range addrs {
+		switch mode {
+		case chunk.ModeSetSync:
+			err = db.setSync(addr)
+		case chunk.ModeSetAccess:
+			err = db.setAccess(addr)
+		case chunk.ModeSetPin:
+			err = db.setPin(addr)
+		case chunk.ModeSetUnpin:
+			err = db.setUnpin(addr)
+		case chunk.ModeSetRemove:
+			err = db.setRemove(addr)
+		default:
+			err = ErrInvalidMode
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get retrieves the stored IndexItem for the given address from the
+// appropriate retrieval index, without any other side effect.
+func (db *DB) get(addr chunk.Address) (item shed.IndexItem, err error) {
+	keyItem := addressToItem(addr)
+	if db.useRetrievalCompositeIndex {
+		item, err = db.retrievalCompositeIndex.Get(keyItem)
+	} else {
+		item, err = db.retrievalDataIndex.Get(keyItem)
+		if err != nil {
+			return item, err
+		}
+		if accessItem, err := db.retrievalAccessIndex.Get(keyItem); err == nil {
+			item.AccessTimestamp = accessItem.AccessTimestamp
+		} else if err != leveldb.ErrNotFound {
+			return item, err
+		}
+	}
+	if err != nil {
+		return item, err
+	}
+	// The retrieval indexes do not key on proximity order, so it is
+	// recomputed here rather than relying on a zero value from them.
+	item.PO = uint8(chunk.Proximity(db.baseKey, addr))
+	return item, nil
+}
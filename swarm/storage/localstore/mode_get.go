@@ -0,0 +1,76 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// setAccess fetches the chunk with the given address and bumps its
+// position in gcIndex, without returning its data.
+func (db *DB) setAccess(addr chunk.Address) (err error) {
+	item, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+	return db.updateGCItem(item)
+}
+
+// updateGCItem bumps the access timestamp of a chunk that has just
+// been read, moving its position in gcIndex so that it is evicted
+// later. item is the value returned by DB.get prior to the access,
+// carrying the previous AccessTimestamp, if any. A pinned chunk is
+// never (re)inserted into gcIndex, so that reading it repeatedly
+// cannot defeat its GC exclusion.
+func (db *DB) updateGCItem(item shed.IndexItem) (err error) {
+	previousAccessTimestamp := item.AccessTimestamp
+	item.AccessTimestamp = now()
+
+	if !db.useRetrievalCompositeIndex {
+		if err := db.retrievalAccessIndex.Put(item); err != nil {
+			return err
+		}
+	} else {
+		if err := db.retrievalCompositeIndex.Put(item); err != nil {
+			return err
+		}
+	}
+
+	if previousAccessTimestamp != 0 {
+		err = db.gcIndex.Delete(shed.IndexItem{
+			Address:         item.Address,
+			StoreTimestamp:  item.StoreTimestamp,
+			AccessTimestamp: previousAccessTimestamp,
+		})
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+	}
+
+	pinned, err := db.isPinned(item.Address)
+	if err != nil {
+		return err
+	}
+	if pinned {
+		return nil
+	}
+
+	return db.gcIndex.Put(item)
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// putSync stores a chunk that was received from the syncer, adding
+// it to the retrieval and pull indexes, but not to the push index as
+// it did not originate locally.
+func (db *DB) putSync(ch chunk.Chunk) (err error) {
+	item, err := db.newStoredItem(ch)
+	if err != nil {
+		return err
+	}
+	if err := db.putRetrieval(item); err != nil {
+		return err
+	}
+	if err := db.pullIndex.Put(item); err != nil {
+		return err
+	}
+	db.triggerPullSubscriptions(item.PO)
+	return db.incSize()
+}
+
+// putUpload stores a locally added chunk, adding it to the retrieval,
+// pull and push indexes so that it is both synced to its
+// neighbourhood and retrievable locally.
+func (db *DB) putUpload(ch chunk.Chunk) (err error) {
+	item, err := db.newStoredItem(ch)
+	if err != nil {
+		return err
+	}
+	if err := db.putRetrieval(item); err != nil {
+		return err
+	}
+	if err := db.pullIndex.Put(item); err != nil {
+		return err
+	}
+	db.triggerPullSubscriptions(item.PO)
+	if err := db.pushIndex.Put(item); err != nil {
+		return err
+	}
+	db.triggerPushSubscriptions()
+	return db.incSize()
+}
+
+// newStoredItem builds the IndexItem for a chunk that is about to be
+// stored, assigning it the next BinID of its proximity order bin so
+// that both the retrieval and pull indexes agree on it.
+func (db *DB) newStoredItem(ch chunk.Chunk) (item shed.IndexItem, err error) {
+	po := uint8(chunk.Proximity(db.baseKey, ch.Address()))
+	binID, err := db.nextBinID(po)
+	if err != nil {
+		return item, err
+	}
+	return shed.IndexItem{
+		Address:        ch.Address(),
+		Data:           ch.Data(),
+		StoreTimestamp: now(),
+		PO:             po,
+		BinID:          binID,
+	}, nil
+}
+
+// putRequest stores a chunk that arrived as a response to a retrieval
+// request, marking it as accessed immediately so that it becomes a
+// garbage collection candidate straight away.
+func (db *DB) putRequest(ch chunk.Chunk) (err error) {
+	t := now()
+	item := shed.IndexItem{
+		Address:         ch.Address(),
+		Data:            ch.Data(),
+		StoreTimestamp:  t,
+		AccessTimestamp: t,
+	}
+	if db.useRetrievalCompositeIndex {
+		if err := db.retrievalCompositeIndex.Put(item); err != nil {
+			return err
+		}
+	} else {
+		if err := db.retrievalDataIndex.Put(item); err != nil {
+			return err
+		}
+		if err := db.retrievalAccessIndex.Put(item); err != nil {
+			return err
+		}
+	}
+	return db.gcIndex.Put(item)
+}
+
+// setSync is called once a synced chunk has been confirmed to be
+// stored by its neighbourhood. It removes the chunk from the push
+// index and makes it a garbage collection candidate, unless it is
+// pinned, in which case it is left out of gcIndex.
+func (db *DB) setSync(addr chunk.Address) (err error) {
+	storeItem, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+	if err := db.pushIndex.Delete(shed.IndexItem{
+		Address:        addr,
+		StoreTimestamp: storeItem.StoreTimestamp,
+	}); err != nil {
+		return err
+	}
+	pinned, err := db.isPinned(addr)
+	if err != nil {
+		return err
+	}
+	if pinned {
+		return nil
+	}
+	return db.gcIndex.Put(shed.IndexItem{
+		Address:         addr,
+		StoreTimestamp:  storeItem.StoreTimestamp,
+		AccessTimestamp: storeItem.StoreTimestamp,
+	})
+}
+
+// putRetrieval writes chunk data to the retrieval index in use. The
+// composite index keeps data and access time together, so its access
+// timestamp is initialized to the store timestamp until the chunk is
+// actually accessed.
+func (db *DB) putRetrieval(item shed.IndexItem) (err error) {
+	if db.useRetrievalCompositeIndex {
+		if item.AccessTimestamp == 0 {
+			item.AccessTimestamp = item.StoreTimestamp
+		}
+		return db.retrievalCompositeIndex.Put(item)
+	}
+	return db.retrievalDataIndex.Put(item)
+}
+
+// incSize increments the persisted chunk counter.
+func (db *DB) incSize() (err error) {
+	size, err := db.sizeCounter.Get()
+	if err != nil {
+		return err
+	}
+	return db.sizeCounter.Put(size + 1)
+}
+
+// decSize decrements the persisted chunk counter.
+func (db *DB) decSize() (err error) {
+	size, err := db.sizeCounter.Get()
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	return db.sizeCounter.Put(size - 1)
+}
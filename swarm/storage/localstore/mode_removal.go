@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// ErrChunkPinned is returned by ModeSetRemove when a chunk still has
+// a non-zero pin counter, refusing to remove it from the store.
+var ErrChunkPinned = errChunkPinned{}
+
+type errChunkPinned struct{}
+
+func (errChunkPinned) Error() string { return "localstore: chunk is pinned" }
+
+// setRemove removes a chunk from all indexes and decrements the
+// persisted chunk counter. It is triggered internally, typically by
+// the garbage collector. Pinned chunks are never removed.
+func (db *DB) setRemove(addr chunk.Address) (err error) {
+	pinned, err := db.isPinned(addr)
+	if err != nil {
+		return err
+	}
+	if pinned {
+		return ErrChunkPinned
+	}
+
+	item, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+
+	if db.useRetrievalCompositeIndex {
+		if err := db.retrievalCompositeIndex.Delete(item); err != nil {
+			return err
+		}
+	} else {
+		if err := db.retrievalDataIndex.Delete(item); err != nil {
+			return err
+		}
+		if err := db.retrievalAccessIndex.Delete(item); err != nil {
+			return err
+		}
+	}
+
+	if err := db.pullIndex.Delete(shed.IndexItem{
+		Address: addr,
+		PO:      item.PO,
+		BinID:   item.BinID,
+	}); err != nil {
+		return err
+	}
+
+	if err := db.pushIndex.Delete(shed.IndexItem{
+		Address:        addr,
+		StoreTimestamp: item.StoreTimestamp,
+	}); err != nil {
+		return err
+	}
+
+	if err := db.gcIndex.Delete(shed.IndexItem{
+		Address:         addr,
+		StoreTimestamp:  item.StoreTimestamp,
+		AccessTimestamp: item.AccessTimestamp,
+	}); err != nil {
+		return err
+	}
+
+	if err := db.removePostageStamp(item); err != nil {
+		return err
+	}
+
+	return db.decSize()
+}
+
+// removePostageStamp deletes the postage stamp of a chunk being
+// removed, if it has one, keeping reserveSizeCounter in sync.
+func (db *DB) removePostageStamp(item shed.IndexItem) (err error) {
+	stamp, err := db.postageIndex.Get(addressToItem(item.Address))
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if err := db.postageChunksIndex.Delete(shed.IndexItem{
+		BatchID:    stamp.BatchID,
+		PO:         item.PO,
+		StampIndex: stamp.StampIndex,
+	}); err != nil {
+		return err
+	}
+	if err := db.postageIndex.Delete(addressToItem(item.Address)); err != nil {
+		return err
+	}
+	if item.PO >= stamp.Depth {
+		return db.decReserveSize()
+	}
+	return nil
+}
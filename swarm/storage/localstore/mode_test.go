@@ -26,34 +26,34 @@ import (
 
 	"github.com/syndtr/goleveldb/leveldb"
 
+	"github.com/ethereum/go-ethereum/swarm/chunk"
 	"github.com/ethereum/go-ethereum/swarm/shed"
-	"github.com/ethereum/go-ethereum/swarm/storage"
 )
 
-// TestModeSyncing validates internal data operations and state
-// for ModeSyncing on DB with default configuration.
-func TestModeSyncing(t *testing.T) {
+// TestModePutSync validates internal data operations and state
+// for ModePutSync on DB with default configuration.
+func TestModePutSync(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeSyncingValues(t, db)
+	testModePutSyncValues(t, db)
 }
 
-// TestModeSyncing_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeSyncing on DB with
+// TestModePutSync_useRetrievalCompositeIndex validates internal
+// data operations and state for ModePutSync on DB with
 // retrieval composite index enabled.
-func TestModeSyncing_useRetrievalCompositeIndex(t *testing.T) {
+func TestModePutSync_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeSyncingValues(t, db)
+	testModePutSyncValues(t, db)
 }
 
-// testModeSyncingValues validates ModeSyncing index values on the provided DB.
-func testModeSyncingValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeSyncing)
+// testModePutSyncValues validates ModePutSync index values on the provided DB.
+func testModePutSyncValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutSync, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
 	wantTimestamp := time.Now().UTC().UnixNano()
 	defer func(n func() int64) { now = n }(now)
@@ -66,44 +66,44 @@ func testModeSyncingValues(t *testing.T, db *DB) {
 		t.Fatal(err)
 	}
 
-	err = a.Put(context.Background(), chunk)
+	err = p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	wantSize++
 
-	t.Run("retrieve indexes", testRetrieveIndexesValues(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("retrieve indexes", testRetrieveIndexesValues(db, ch, wantTimestamp, wantTimestamp))
 
-	t.Run("pull index", testPullIndexValues(db, chunk, wantTimestamp, nil))
+	t.Run("pull index", testPullIndexValues(db, ch, wantTimestamp, nil))
 
 	t.Run("size counter", testSizeCounter(db, wantSize))
 }
 
-// TestModeUpload validates internal data operations and state
-// for ModeUpload on DB with default configuration.
-func TestModeUpload(t *testing.T) {
+// TestModePutUpload validates internal data operations and state
+// for ModePutUpload on DB with default configuration.
+func TestModePutUpload(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeUploadValues(t, db)
+	testModePutUploadValues(t, db)
 }
 
-// TestModeUpload_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeUpload on DB with
+// TestModePutUpload_useRetrievalCompositeIndex validates internal
+// data operations and state for ModePutUpload on DB with
 // retrieval composite index enabled.
-func TestModeUpload_useRetrievalCompositeIndex(t *testing.T) {
+func TestModePutUpload_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeUploadValues(t, db)
+	testModePutUploadValues(t, db)
 }
 
-// testModeUploadValues validates ModeUpload index values on the provided DB.
-func testModeUploadValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeUpload)
+// testModePutUploadValues validates ModePutUpload index values on the provided DB.
+func testModePutUploadValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutUpload, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
 	wantTimestamp := time.Now().UTC().UnixNano()
 	defer func(n func() int64) { now = n }(now)
@@ -116,46 +116,84 @@ func testModeUploadValues(t *testing.T, db *DB) {
 		t.Fatal(err)
 	}
 
-	err = a.Put(context.Background(), chunk)
+	err = p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	wantSize++
 
-	t.Run("retrieve indexes", testRetrieveIndexesValues(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("retrieve indexes", testRetrieveIndexesValues(db, ch, wantTimestamp, wantTimestamp))
 
-	t.Run("pull index", testPullIndexValues(db, chunk, wantTimestamp, nil))
+	t.Run("pull index", testPullIndexValues(db, ch, wantTimestamp, nil))
 
-	t.Run("push index", testPushIndexValues(db, chunk, wantTimestamp, nil))
+	t.Run("push index", testPushIndexValues(db, ch, wantTimestamp, nil))
 
 	t.Run("size counter", testSizeCounter(db, wantSize))
 }
 
-// TestModeRequest validates internal data operations and state
-// for ModeRequest on DB with default configuration.
-func TestModeRequest(t *testing.T) {
+// TestModePutUpload_invalidChunk validates that a Putter with a
+// Validator that rejects a chunk returns ErrInvalidChunk and does
+// not touch any index.
+func TestModePutUpload_invalidChunk(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeRequestValues(t, db)
+	p := db.NewPutter(chunk.ModePutUpload, rejectAllValidator{})
+
+	ch := generateRandomChunk()
+
+	wantSize, err := db.sizeCounter.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Put(context.Background(), ch)
+	if err != ErrInvalidChunk {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidChunk)
+	}
+
+	t.Run("retrieve data index count", testIndexItemsCount(db.retrievalDataIndex, 0))
+
+	t.Run("pull index count", testIndexItemsCount(db.pullIndex, 0))
+
+	t.Run("push index count", testIndexItemsCount(db.pushIndex, 0))
+
+	t.Run("size counter", testSizeCounter(db, wantSize))
+}
+
+// rejectAllValidator is a chunk.Validator that rejects every chunk,
+// used to test that Putter.Put refuses to store invalid chunks.
+type rejectAllValidator struct{}
+
+func (rejectAllValidator) Validate(addr chunk.Address, data []byte) bool {
+	return false
+}
+
+// TestModePutRequest validates internal data operations and state
+// for ModePutRequest on DB with default configuration.
+func TestModePutRequest(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	testModePutRequestValues(t, db)
 }
 
-// TestModeRequest_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeRequest on DB with
+// TestModePutRequest_useRetrievalCompositeIndex validates internal
+// data operations and state for ModePutRequest on DB with
 // retrieval composite index enabled.
-func TestModeRequest_useRetrievalCompositeIndex(t *testing.T) {
+func TestModePutRequest_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeRequestValues(t, db)
+	testModePutRequestValues(t, db)
 }
 
-// testModeRequestValues validates ModeRequest index values on the provided DB.
-func testModeRequestValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeRequest)
+// testModePutRequestValues validates ModePutRequest index values on the provided DB.
+func testModePutRequestValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutRequest, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
 	wantTimestamp := time.Now().UTC().UnixNano()
 	defer func(n func() int64) { now = n }(now)
@@ -163,40 +201,40 @@ func testModeRequestValues(t *testing.T, db *DB) {
 		return wantTimestamp
 	}
 
-	err := a.Put(context.Background(), chunk)
+	err := p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, ch, wantTimestamp, wantTimestamp))
 
-	t.Run("gc index", testGCIndexValues(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("gc index", testGCIndexValues(db, ch, wantTimestamp, wantTimestamp))
 }
 
-// TestModeSynced validates internal data operations and state
-// for ModeSynced on DB with default configuration.
-func TestModeSynced(t *testing.T) {
+// TestModeSetSync validates internal data operations and state
+// for ModeSetSync on DB with default configuration.
+func TestModeSetSync(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeSyncedValues(t, db)
+	testModeSetSyncValues(t, db)
 }
 
-// TestModeSynced_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeSynced on DB with
+// TestModeSetSync_useRetrievalCompositeIndex validates internal
+// data operations and state for ModeSetSync on DB with
 // retrieval composite index enabled.
-func TestModeSynced_useRetrievalCompositeIndex(t *testing.T) {
+func TestModeSetSync_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeSyncedValues(t, db)
+	testModeSetSyncValues(t, db)
 }
 
-// testModeSyncedValues validates ModeSynced index values on the provided DB.
-func testModeSyncedValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeSyncing)
+// testModeSetSyncValues validates ModeSetSync index values on the provided DB.
+func testModeSetSyncValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutSync, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
 	wantTimestamp := time.Now().UTC().UnixNano()
 	defer func(n func() int64) { now = n }(now)
@@ -204,49 +242,47 @@ func testModeSyncedValues(t *testing.T, db *DB) {
 		return wantTimestamp
 	}
 
-	err := a.Put(context.Background(), chunk)
+	err := p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	a = db.Accessor(ModeSynced)
-
-	err = a.Put(context.Background(), chunk)
+	err = db.Set(context.Background(), chunk.ModeSetSync, ch.Address())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Run("retrieve indexes", testRetrieveIndexesValues(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("retrieve indexes", testRetrieveIndexesValues(db, ch, wantTimestamp, wantTimestamp))
 
-	t.Run("push index", testPushIndexValues(db, chunk, wantTimestamp, leveldb.ErrNotFound))
+	t.Run("push index", testPushIndexValues(db, ch, wantTimestamp, leveldb.ErrNotFound))
 
-	t.Run("gc index", testGCIndexValues(db, chunk, wantTimestamp, wantTimestamp))
+	t.Run("gc index", testGCIndexValues(db, ch, wantTimestamp, wantTimestamp))
 }
 
-// TestModeAccess validates internal data operations and state
-// for ModeAccess on DB with default configuration.
-func TestModeAccess(t *testing.T) {
+// TestModeGetRequest validates internal data operations and state
+// for ModeGetRequest on DB with default configuration.
+func TestModeGetRequest(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeAccessValues(t, db)
+	testModeGetRequestValues(t, db)
 }
 
-// TestModeAccess_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeAccess on DB with
+// TestModeGetRequest_useRetrievalCompositeIndex validates internal
+// data operations and state for ModeGetRequest on DB with
 // retrieval composite index enabled.
-func TestModeAccess_useRetrievalCompositeIndex(t *testing.T) {
+func TestModeGetRequest_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeAccessValues(t, db)
+	testModeGetRequestValues(t, db)
 }
 
-// testModeAccessValues validates ModeAccess index values on the provided DB.
-func testModeAccessValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeUpload)
+// testModeGetRequestValues validates ModeGetRequest index values on the provided DB.
+func testModeGetRequestValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutUpload, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
 	uploadTimestamp := time.Now().UTC().UnixNano()
 	defer func(n func() int64) { now = n }(now)
@@ -254,30 +290,30 @@ func testModeAccessValues(t *testing.T, db *DB) {
 		return uploadTimestamp
 	}
 
-	err := a.Put(context.Background(), chunk)
+	err := p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	a = db.Accessor(modeAccess)
+	g := db.NewGetter(chunk.ModeGetRequest)
 
 	t.Run("first get", func(t *testing.T) {
-		got, err := a.Get(context.Background(), chunk.Address())
+		got, err := g.Get(context.Background(), ch.Address())
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if !bytes.Equal(chunk.Address(), got.Address()) {
-			t.Errorf("got chunk address %x, want %s", chunk.Address(), got.Address())
+		if !bytes.Equal(ch.Address(), got.Address()) {
+			t.Errorf("got chunk address %x, want %s", ch.Address(), got.Address())
 		}
 
-		if !bytes.Equal(chunk.Data(), got.Data()) {
-			t.Errorf("got chunk data %x, want %s", chunk.Data(), got.Data())
+		if !bytes.Equal(ch.Data(), got.Data()) {
+			t.Errorf("got chunk data %x, want %s", ch.Data(), got.Data())
 		}
 
-		t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, chunk, uploadTimestamp, uploadTimestamp))
+		t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, ch, uploadTimestamp, uploadTimestamp))
 
-		t.Run("gc index", testGCIndexValues(db, chunk, uploadTimestamp, uploadTimestamp))
+		t.Run("gc index", testGCIndexValues(db, ch, uploadTimestamp, uploadTimestamp))
 
 		t.Run("gc index count", testIndexItemsCount(db.gcIndex, 1))
 	})
@@ -288,59 +324,57 @@ func testModeAccessValues(t *testing.T, db *DB) {
 			return accessTimestamp
 		}
 
-		got, err := a.Get(context.Background(), chunk.Address())
+		got, err := g.Get(context.Background(), ch.Address())
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if !bytes.Equal(chunk.Address(), got.Address()) {
-			t.Errorf("got chunk address %x, want %s", chunk.Address(), got.Address())
+		if !bytes.Equal(ch.Address(), got.Address()) {
+			t.Errorf("got chunk address %x, want %s", ch.Address(), got.Address())
 		}
 
-		if !bytes.Equal(chunk.Data(), got.Data()) {
-			t.Errorf("got chunk data %x, want %s", chunk.Data(), got.Data())
+		if !bytes.Equal(ch.Data(), got.Data()) {
+			t.Errorf("got chunk data %x, want %s", ch.Data(), got.Data())
 		}
 
-		t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, chunk, uploadTimestamp, accessTimestamp))
+		t.Run("retrieve indexes", testRetrieveIndexesValuesWithAccess(db, ch, uploadTimestamp, accessTimestamp))
 
-		t.Run("gc index", testGCIndexValues(db, chunk, uploadTimestamp, accessTimestamp))
+		t.Run("gc index", testGCIndexValues(db, ch, uploadTimestamp, accessTimestamp))
 
 		t.Run("gc index count", testIndexItemsCount(db.gcIndex, 1))
 	})
 }
 
-// TestModeRemoval validates internal data operations and state
-// for ModeRemoval on DB with default configuration.
-func TestModeRemoval(t *testing.T) {
+// TestModeSetRemove validates internal data operations and state
+// for ModeSetRemove on DB with default configuration.
+func TestModeSetRemove(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	testModeRemovalValues(t, db)
+	testModeSetRemoveValues(t, db)
 }
 
-// TestModeRemoval_useRetrievalCompositeIndex validates internal
-// data operations and state for ModeRemoval on DB with
+// TestModeSetRemove_useRetrievalCompositeIndex validates internal
+// data operations and state for ModeSetRemove on DB with
 // retrieval composite index enabled.
-func TestModeRemoval_useRetrievalCompositeIndex(t *testing.T) {
+func TestModeSetRemove_useRetrievalCompositeIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
 	defer cleanupFunc()
 
-	testModeRemovalValues(t, db)
+	testModeSetRemoveValues(t, db)
 }
 
-// testModeRemovalValues validates ModeRemoval index values on the provided DB.
-func testModeRemovalValues(t *testing.T, db *DB) {
-	a := db.Accessor(ModeUpload)
+// testModeSetRemoveValues validates ModeSetRemove index values on the provided DB.
+func testModeSetRemoveValues(t *testing.T, db *DB) {
+	p := db.NewPutter(chunk.ModePutUpload, nil)
 
-	chunk := generateRandomChunk()
+	ch := generateRandomChunk()
 
-	err := a.Put(context.Background(), chunk)
+	err := p.Put(context.Background(), ch)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	a = db.Accessor(modeRemoval)
-
 	wantSize, err := db.sizeCounter.Get()
 	if err != nil {
 		t.Fatal(err)
@@ -348,7 +382,7 @@ func testModeRemovalValues(t *testing.T, db *DB) {
 
 	wantSize--
 
-	err = a.Put(context.Background(), chunk)
+	err = db.Set(context.Background(), chunk.ModeSetRemove, ch.Address())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -356,20 +390,20 @@ func testModeRemovalValues(t *testing.T, db *DB) {
 	t.Run("retrieve indexes", func(t *testing.T) {
 		wantErr := leveldb.ErrNotFound
 		if db.useRetrievalCompositeIndex {
-			_, err := db.retrievalCompositeIndex.Get(addressToItem(chunk.Address()))
+			_, err := db.retrievalCompositeIndex.Get(addressToItem(ch.Address()))
 			if err != wantErr {
 				t.Errorf("got error %v, want %v", err, wantErr)
 			}
 			t.Run("retrieve index count", testIndexItemsCount(db.retrievalCompositeIndex, 0))
 		} else {
-			_, err := db.retrievalDataIndex.Get(addressToItem(chunk.Address()))
+			_, err := db.retrievalDataIndex.Get(addressToItem(ch.Address()))
 			if err != wantErr {
 				t.Errorf("got error %v, want %v", err, wantErr)
 			}
 			t.Run("retrieve data index count", testIndexItemsCount(db.retrievalDataIndex, 0))
 
 			// access index should not be set
-			_, err = db.retrievalAccessIndex.Get(addressToItem(chunk.Address()))
+			_, err = db.retrievalAccessIndex.Get(addressToItem(ch.Address()))
 			if err != wantErr {
 				t.Errorf("got error %v, want %v", err, wantErr)
 			}
@@ -377,7 +411,7 @@ func testModeRemovalValues(t *testing.T, db *DB) {
 		}
 	})
 
-	t.Run("pull index", testPullIndexValues(db, chunk, 0, leveldb.ErrNotFound))
+	t.Run("pull index", testPullIndexValues(db, ch, 0, leveldb.ErrNotFound))
 
 	t.Run("pull index count", testIndexItemsCount(db.pullIndex, 0))
 
@@ -387,46 +421,42 @@ func testModeRemovalValues(t *testing.T, db *DB) {
 }
 
 // TestDB_pullIndex validates the ordering of keys in pull index.
-// Pull index key contains PO prefix which is calculated from
-// DB base key and chunk address. This is not an IndexItem field
-// which are checked in Mode tests.
+// Pull index key contains a PO prefix which is calculated from
+// DB base key and chunk address, followed by a BinID that is
+// assigned monotonically per bin at insertion time. Neither is an
+// IndexItem field that is otherwise checked in Mode tests.
 // This test uploads chunks, sorts them in expected order and
 // validates that pull index iterator will iterate it the same
-// order.
+// order, with strictly increasing BinID values within every bin.
 func TestDB_pullIndex(t *testing.T) {
 	db, cleanupFunc := newTestDB(t, nil)
 	defer cleanupFunc()
 
-	a := db.Accessor(ModeUpload)
+	p := db.NewPutter(chunk.ModePutUpload, nil)
 
 	chunkCount := 50
 
 	// a wrapper around Chunk to keep
-	// store timestamp for sorting
+	// upload order for sorting
 	type testChunk struct {
-		storage.Chunk
-		storeTimestamp int64
+		chunk.Chunk
+		uploadOrder int
 	}
 
 	chunks := make([]testChunk, chunkCount)
 
 	// upload random chunks
 	for i := 0; i < chunkCount; i++ {
-		chunk := generateRandomChunk()
+		ch := generateRandomChunk()
 
-		err := a.Put(context.Background(), chunk)
+		err := p.Put(context.Background(), ch)
 		if err != nil {
 			t.Fatal(err)
 		}
 
 		chunks[i] = testChunk{
-			Chunk: chunk,
-			// this timestamp is not the same as in
-			// the index, but given that uploads
-			// are sequential and that only ordering
-			// of events matter, this information is
-			// sufficient
-			storeTimestamp: now(),
+			Chunk:       ch,
+			uploadOrder: i,
 		}
 	}
 
@@ -434,33 +464,35 @@ func TestDB_pullIndex(t *testing.T) {
 	testIndexItemsCount(db.pullIndex, chunkCount)
 
 	// sort uploaded chunk is an expected pull index keys order
-	// "PO|StoredTimestamp|Hash"
+	// "PO|BinID|Hash"
 	sort.Slice(chunks, func(i, j int) (less bool) {
-		poi := storage.Proximity(db.baseKey, chunks[i].Address())
-		poj := storage.Proximity(db.baseKey, chunks[j].Address())
+		poi := chunk.Proximity(db.baseKey, chunks[i].Address())
+		poj := chunk.Proximity(db.baseKey, chunks[j].Address())
 		if poi < poj {
 			return true
 		}
 		if poi > poj {
 			return false
 		}
-		if chunks[i].storeTimestamp < chunks[j].storeTimestamp {
-			return true
-		}
-		if chunks[i].storeTimestamp > chunks[j].storeTimestamp {
-			return false
-		}
-		return bytes.Compare(chunks[i].Address(), chunks[j].Address()) == -1
+		// within the same bin, BinID assignment follows upload order
+		return chunks[i].uploadOrder < chunks[j].uploadOrder
 	})
 
-	// iterate over all items
+	// iterate over all items, validating both the address order and
+	// that BinID is strictly increasing within every bin
 	var cursor int
+	lastBinID := make(map[uint8]uint64)
 	err := db.pullIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
 		want := chunks[cursor].Address()
 		got := item.Address
 		if !bytes.Equal(got, want) {
 			return true, fmt.Errorf("got address %x at position %v, want %x", got, cursor, want)
 		}
+		po := item.PO
+		if last, ok := lastBinID[po]; ok && item.BinID <= last {
+			return true, fmt.Errorf("got non-increasing BinID %v after %v in bin %v", item.BinID, last, po)
+		}
+		lastBinID[po] = item.BinID
 		cursor++
 		return false, nil
 	})
@@ -471,24 +503,24 @@ func TestDB_pullIndex(t *testing.T) {
 
 // testRetrieveIndexesValues returns a test function that validates if the right
 // chunk values are in the retrieval indexes.
-func testRetrieveIndexesValues(db *DB, chunk storage.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
+func testRetrieveIndexesValues(db *DB, ch chunk.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
 	return func(t *testing.T) {
 		if db.useRetrievalCompositeIndex {
-			item, err := db.retrievalCompositeIndex.Get(addressToItem(chunk.Address()))
+			item, err := db.retrievalCompositeIndex.Get(addressToItem(ch.Address()))
 			if err != nil {
 				t.Fatal(err)
 			}
-			validateItem(t, item, chunk.Address(), chunk.Data(), storeTimestamp, accessTimestamp)
+			validateItem(t, item, ch.Address(), ch.Data(), storeTimestamp, accessTimestamp)
 		} else {
-			item, err := db.retrievalDataIndex.Get(addressToItem(chunk.Address()))
+			item, err := db.retrievalDataIndex.Get(addressToItem(ch.Address()))
 			if err != nil {
 				t.Fatal(err)
 			}
-			validateItem(t, item, chunk.Address(), chunk.Data(), storeTimestamp, 0)
+			validateItem(t, item, ch.Address(), ch.Data(), storeTimestamp, 0)
 
 			// access index should not be set
 			wantErr := leveldb.ErrNotFound
-			item, err = db.retrievalAccessIndex.Get(addressToItem(chunk.Address()))
+			item, err = db.retrievalAccessIndex.Get(addressToItem(ch.Address()))
 			if err != wantErr {
 				t.Errorf("got error %v, want %v", err, wantErr)
 			}
@@ -498,78 +530,99 @@ func testRetrieveIndexesValues(db *DB, chunk storage.Chunk, storeTimestamp, acce
 
 // testRetrieveIndexesValuesWithAccess returns a test function that validates if the right
 // chunk values are in the retrieval indexes when access time must be stored.
-func testRetrieveIndexesValuesWithAccess(db *DB, chunk storage.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
+func testRetrieveIndexesValuesWithAccess(db *DB, ch chunk.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
 	return func(t *testing.T) {
 		if db.useRetrievalCompositeIndex {
-			item, err := db.retrievalCompositeIndex.Get(addressToItem(chunk.Address()))
+			item, err := db.retrievalCompositeIndex.Get(addressToItem(ch.Address()))
 			if err != nil {
 				t.Fatal(err)
 			}
-			validateItem(t, item, chunk.Address(), chunk.Data(), storeTimestamp, accessTimestamp)
+			validateItem(t, item, ch.Address(), ch.Data(), storeTimestamp, accessTimestamp)
 		} else {
-			item, err := db.retrievalDataIndex.Get(addressToItem(chunk.Address()))
+			item, err := db.retrievalDataIndex.Get(addressToItem(ch.Address()))
 			if err != nil {
 				t.Fatal(err)
 			}
-			validateItem(t, item, chunk.Address(), chunk.Data(), storeTimestamp, 0)
+			validateItem(t, item, ch.Address(), ch.Data(), storeTimestamp, 0)
 
 			// access index should not be set
-			item, err = db.retrievalAccessIndex.Get(addressToItem(chunk.Address()))
+			item, err = db.retrievalAccessIndex.Get(addressToItem(ch.Address()))
 			if err != nil {
 				t.Fatal(err)
 			}
-			validateItem(t, item, chunk.Address(), nil, 0, accessTimestamp)
+			validateItem(t, item, ch.Address(), nil, 0, accessTimestamp)
 		}
 	}
 }
 
 // testPullIndexValues returns a test function that validates if the right
-// chunk values are in the pull index.
-func testPullIndexValues(db *DB, chunk storage.Chunk, storeTimestamp int64, wantError error) func(t *testing.T) {
+// chunk values are in the pull index. As the pull index key is now
+// PO|BinID|Hash rather than PO|StoreTimestamp|Hash, the entry is
+// located by scanning for its address instead of building the key
+// directly from storeTimestamp.
+func testPullIndexValues(db *DB, ch chunk.Chunk, storeTimestamp int64, wantError error) func(t *testing.T) {
 	return func(t *testing.T) {
-		item, err := db.pullIndex.Get(shed.IndexItem{
-			Address:        chunk.Address(),
-			StoreTimestamp: storeTimestamp,
-		})
+		item, err := pullIndexItemByAddress(db, ch.Address())
 		if err != wantError {
 			t.Errorf("got error %v, want %v", err, wantError)
 		}
 		if err == nil {
-			validateItem(t, item, chunk.Address(), nil, storeTimestamp, 0)
+			validateItem(t, item, ch.Address(), nil, storeTimestamp, 0)
 		}
 	}
 }
 
+// pullIndexItemByAddress returns the pullIndex entry for the given
+// address, or leveldb.ErrNotFound if no such entry exists.
+func pullIndexItemByAddress(db *DB, addr chunk.Address) (item shed.IndexItem, err error) {
+	var found bool
+	walkErr := db.pullIndex.IterateAll(func(i shed.IndexItem) (stop bool, err error) {
+		if bytes.Equal(i.Address, addr) {
+			item = i
+			found = true
+			return true, nil
+		}
+		return false, nil
+	})
+	if walkErr != nil {
+		return item, walkErr
+	}
+	if !found {
+		return item, leveldb.ErrNotFound
+	}
+	return item, nil
+}
+
 // testPushIndexValues returns a test function that validates if the right
 // chunk values are in the push index.
-func testPushIndexValues(db *DB, chunk storage.Chunk, storeTimestamp int64, wantError error) func(t *testing.T) {
+func testPushIndexValues(db *DB, ch chunk.Chunk, storeTimestamp int64, wantError error) func(t *testing.T) {
 	return func(t *testing.T) {
 		item, err := db.pushIndex.Get(shed.IndexItem{
-			Address:        chunk.Address(),
+			Address:        ch.Address(),
 			StoreTimestamp: storeTimestamp,
 		})
 		if err != wantError {
 			t.Errorf("got error %v, want %v", err, wantError)
 		}
 		if err == nil {
-			validateItem(t, item, chunk.Address(), nil, storeTimestamp, 0)
+			validateItem(t, item, ch.Address(), nil, storeTimestamp, 0)
 		}
 	}
 }
 
 // testGCIndexValues returns a test function that validates if the right
 // chunk values are in the push index.
-func testGCIndexValues(db *DB, chunk storage.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
+func testGCIndexValues(db *DB, ch chunk.Chunk, storeTimestamp, accessTimestamp int64) func(t *testing.T) {
 	return func(t *testing.T) {
 		item, err := db.gcIndex.Get(shed.IndexItem{
-			Address:         chunk.Address(),
+			Address:         ch.Address(),
 			StoreTimestamp:  storeTimestamp,
 			AccessTimestamp: accessTimestamp,
 		})
 		if err != nil {
 			t.Fatal(err)
 		}
-		validateItem(t, item, chunk.Address(), nil, storeTimestamp, accessTimestamp)
+		validateItem(t, item, ch.Address(), nil, storeTimestamp, accessTimestamp)
 	}
 }
 
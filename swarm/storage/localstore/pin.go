@@ -0,0 +1,155 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// ErrNotPinned is returned by ModeSetUnpin when a chunk has no
+// outstanding pins.
+var ErrNotPinned = errNotPinned{}
+
+type errNotPinned struct{}
+
+func (errNotPinned) Error() string { return "localstore: chunk is not pinned" }
+
+// putUploadPin stores ch as ModePutUpload would if it is not yet
+// present, then pins it. It exists because ModeSetPin only carries
+// an address and therefore requires the chunk to already be stored;
+// putUploadPin is used instead to pin a chunk that may not have been
+// uploaded yet, such as right after it is created.
+func (db *DB) putUploadPin(ch chunk.Chunk) (err error) {
+	_, err = db.get(ch.Address())
+	switch err {
+	case nil:
+	case leveldb.ErrNotFound:
+		if err := db.putUpload(ch); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	return db.setPin(ch.Address())
+}
+
+// setPin increments the pin counter for an already stored chunk.
+// While pinCounter is greater than zero the chunk is excluded from
+// gcIndex, so that it is never selected by the garbage collector.
+// The chunk must already be stored; use ModePutUploadPin to pin one
+// that might not be.
+func (db *DB) setPin(addr chunk.Address) (err error) {
+	storeItem, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+
+	item, err := db.pinIndex.Get(addressToItem(addr))
+	if err != nil {
+		if err != leveldb.ErrNotFound {
+			return err
+		}
+		item = shed.IndexItem{
+			Address: addr,
+		}
+	}
+
+	if item.PinCounter == 0 {
+		// the chunk becomes pinned: exclude it from eviction
+		if err := db.gcIndex.Delete(shed.IndexItem{
+			Address:         addr,
+			StoreTimestamp:  storeItem.StoreTimestamp,
+			AccessTimestamp: storeItem.AccessTimestamp,
+		}); err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+	}
+
+	item.PinCounter++
+	return db.pinIndex.Put(item)
+}
+
+// setUnpin decrements the pin counter for the chunk, removing the
+// pinIndex entry once it reaches zero. The chunk then becomes a
+// regular gc candidate again, using its last known store and access
+// timestamps.
+func (db *DB) setUnpin(addr chunk.Address) (err error) {
+	item, err := db.pinIndex.Get(addressToItem(addr))
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return ErrNotPinned
+		}
+		return err
+	}
+	if item.PinCounter == 0 {
+		return ErrNotPinned
+	}
+
+	item.PinCounter--
+	if item.PinCounter > 0 {
+		return db.pinIndex.Put(item)
+	}
+
+	if err := db.pinIndex.Delete(addressToItem(addr)); err != nil {
+		return err
+	}
+
+	storeItem, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+	return db.gcIndex.Put(gcCandidateItem(storeItem))
+}
+
+// GetPinnedChunks returns the addresses of all currently pinned
+// chunks, together with their pin counters.
+func (db *DB) GetPinnedChunks() (pinned []PinnedChunk, err error) {
+	err = db.pinIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+		pinned = append(pinned, PinnedChunk{
+			Address:    item.Address,
+			PinCounter: item.PinCounter,
+		})
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pinned, nil
+}
+
+// PinnedChunk holds the address and the reference count of a pinned
+// chunk, as returned by GetPinnedChunks.
+type PinnedChunk struct {
+	Address    chunk.Address
+	PinCounter uint64
+}
+
+// isPinned reports whether the chunk with the given address has a
+// non-zero pin counter.
+func (db *DB) isPinned(addr chunk.Address) (bool, error) {
+	item, err := db.pinIndex.Get(addressToItem(addr))
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return item.PinCounter > 0, nil
+}
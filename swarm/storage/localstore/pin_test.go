@@ -0,0 +1,356 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// TestModeSetPin_notStored validates that pinning an address with no
+// stored chunk behind it fails, since ModeSetPin only carries an
+// address and has no chunk data to fall back to.
+func TestModeSetPin_notStored(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	ch := generateRandomChunk()
+	err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address())
+	if err != leveldb.ErrNotFound {
+		t.Errorf("got error %v, want %v", err, leveldb.ErrNotFound)
+	}
+}
+
+// TestModePutUploadPin validates that ModePutUploadPin pins a chunk
+// that has not been stored yet, storing it as ModePutUpload would,
+// and that it also pins a chunk that is already stored without
+// storing it again.
+func TestModePutUploadPin(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploadPinner := db.NewPutter(chunk.ModePutUploadPin, nil)
+
+	t.Run("pins and stores a new chunk", func(t *testing.T) {
+		ch := generateRandomChunk()
+		if err := uploadPinner.Put(context.Background(), ch); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := db.get(ch.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Data, ch.Data()) {
+			t.Errorf("got chunk data %x, want %x", got.Data, ch.Data())
+		}
+
+		item, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.PinCounter != 1 {
+			t.Errorf("got pin counter %v, want 1", item.PinCounter)
+		}
+	})
+
+	t.Run("pins an already stored chunk without storing it again", func(t *testing.T) {
+		ch := generateRandomChunk()
+		uploader := db.NewPutter(chunk.ModePutUpload, nil)
+		if err := uploader.Put(context.Background(), ch); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := uploadPinner.Put(context.Background(), ch); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.PinCounter != 1 {
+			t.Errorf("got pin counter %v, want 1", item.PinCounter)
+		}
+	})
+}
+
+// TestModeSetPin validates internal data operations and state
+// for ModeSetPin/ModeSetUnpin on DB with default configuration.
+func TestModeSetPin(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	testModeSetPinValues(t, db)
+}
+
+// TestModeSetPin_useRetrievalCompositeIndex validates internal data
+// operations and state for ModeSetPin/ModeSetUnpin on DB with
+// retrieval composite index enabled.
+func TestModeSetPin_useRetrievalCompositeIndex(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, &Options{UseRetrievalCompositeIndex: true})
+	defer cleanupFunc()
+
+	testModeSetPinValues(t, db)
+}
+
+// testModeSetPinValues validates ModeSetPin/ModeSetUnpin index values
+// and GC exclusion on the provided DB.
+func testModeSetPinValues(t *testing.T, db *DB) {
+	ch := generateRandomChunk()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	if err := uploader.Put(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("pin inserts chunk and sets counter to 1", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.PinCounter != 1 {
+			t.Errorf("got pin counter %v, want 1", item.PinCounter)
+		}
+
+		got, err := db.get(ch.Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Data, ch.Data()) {
+			t.Errorf("got chunk data %x, want %x", got.Data, ch.Data())
+		}
+	})
+
+	t.Run("pin again increments counter to 2", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.PinCounter != 2 {
+			t.Errorf("got pin counter %v, want 2", item.PinCounter)
+		}
+	})
+
+	t.Run("pinned chunk is excluded from gc index", func(t *testing.T) {
+		err := db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+			if bytes.Equal(item.Address, ch.Address()) {
+				t.Errorf("pinned chunk address %x found in gc index", item.Address)
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("removal of pinned chunk is refused", func(t *testing.T) {
+		err := db.Set(context.Background(), chunk.ModeSetRemove, ch.Address())
+		if err != ErrChunkPinned {
+			t.Errorf("got error %v, want %v", err, ErrChunkPinned)
+		}
+	})
+
+	t.Run("unpin decrements counter to 1", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetUnpin, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.PinCounter != 1 {
+			t.Errorf("got pin counter %v, want 1", item.PinCounter)
+		}
+	})
+
+	t.Run("final unpin removes pinIndex entry and reinserts into gc index", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetUnpin, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := db.pinIndex.Get(addressToItem(ch.Address()))
+		if err == nil {
+			t.Error("got no error, want leveldb.ErrNotFound")
+		}
+
+		var found bool
+		err = db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+			if bytes.Equal(item.Address, ch.Address()) {
+				found = true
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			t.Error("unpinned chunk not found in gc index")
+		}
+	})
+
+	t.Run("unpin of an already unpinned chunk is refused", func(t *testing.T) {
+		err := db.Set(context.Background(), chunk.ModeSetUnpin, ch.Address())
+		if err != ErrNotPinned {
+			t.Errorf("got error %v, want %v", err, ErrNotPinned)
+		}
+	})
+}
+
+// TestModeSetUnpin_neverAccessed validates that unpinning a chunk
+// that was uploaded but never accessed reinserts it into gcIndex
+// keyed by its StoreTimestamp as the access timestamp, the same key
+// setSync would have used for the identical situation, rather than a
+// zero access timestamp.
+func TestModeSetUnpin_neverAccessed(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	ch := generateRandomChunk()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	if err := uploader.Put(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetUnpin, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	storeItem, err := db.get(ch.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storeItem.AccessTimestamp != 0 {
+		t.Fatalf("test chunk unexpectedly has a non-zero access timestamp: %v", storeItem.AccessTimestamp)
+	}
+
+	if _, err := db.gcIndex.Get(shed.IndexItem{
+		Address:         ch.Address(),
+		StoreTimestamp:  storeItem.StoreTimestamp,
+		AccessTimestamp: storeItem.StoreTimestamp,
+	}); err != nil {
+		t.Fatalf("gc index entry not keyed by store timestamp: %v", err)
+	}
+}
+
+// TestModeSetPin_gcExclusion validates that a pinned chunk is not
+// reinserted into gcIndex by paths other than ModeSetPin/ModeSetUnpin
+// themselves, namely a retrieval request, an access bump and a sync
+// confirmation.
+func TestModeSetPin_gcExclusion(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	ch := generateRandomChunk()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	if err := uploader.Put(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertNotInGCIndex := func(t *testing.T) {
+		t.Helper()
+		err := db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+			if bytes.Equal(item.Address, ch.Address()) {
+				t.Errorf("pinned chunk address %x found in gc index", item.Address)
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("retrieval request does not leak into gc index", func(t *testing.T) {
+		getter := db.NewGetter(chunk.ModeGetRequest)
+		if _, err := getter.Get(context.Background(), ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+		assertNotInGCIndex(t)
+	})
+
+	t.Run("access bump does not leak into gc index", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetAccess, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+		assertNotInGCIndex(t)
+	})
+
+	t.Run("sync confirmation does not leak into gc index", func(t *testing.T) {
+		if err := db.Set(context.Background(), chunk.ModeSetSync, ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+		assertNotInGCIndex(t)
+	})
+}
+
+// TestGetPinnedChunks validates that GetPinnedChunks returns exactly
+// the chunks with a non-zero pin counter.
+func TestGetPinnedChunks(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+
+	pinnedChunk := generateRandomChunk()
+	if err := uploader.Put(context.Background(), pinnedChunk); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetPin, pinnedChunk.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	unpinnedChunk := generateRandomChunk()
+	if err := uploader.Put(context.Background(), unpinnedChunk); err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := db.GetPinnedChunks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pinned) != 1 {
+		t.Fatalf("got %v pinned chunks, want 1", len(pinned))
+	}
+	if !bytes.Equal(pinned[0].Address, pinnedChunk.Address()) {
+		t.Errorf("got pinned address %x, want %x", pinned[0].Address, pinnedChunk.Address())
+	}
+	if pinned[0].PinCounter != 1 {
+		t.Errorf("got pin counter %v, want 1", pinned[0].PinCounter)
+	}
+}
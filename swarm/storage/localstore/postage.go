@@ -0,0 +1,371 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// batchIDLength is the length of a postage batch identifier.
+const batchIDLength = 32
+
+// ErrInvalidBatchID is returned by SetPostageStamp when the provided
+// batch identifier does not have the expected length.
+var ErrInvalidBatchID = errInvalidBatchID{}
+
+type errInvalidBatchID struct{}
+
+func (errInvalidBatchID) Error() string { return "localstore: invalid batch id" }
+
+// SetPostageStamp stamps a previously stored chunk with a postage
+// batch, recording the batch's neighbourhood depth at the time of
+// stamping. A chunk is held in the postage reserve, and therefore
+// excluded from the LRU gcIndex, for as long as its proximity order
+// is within the batch's depth. Stamping a chunk that falls outside
+// its batch's depth leaves it as a regular gcIndex candidate.
+func (db *DB) SetPostageStamp(addr chunk.Address, batchID []byte, stampIndex uint64, depth uint8) (err error) {
+	if len(batchID) != batchIDLength {
+		return ErrInvalidBatchID
+	}
+
+	storeItem, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+	po := storeItem.PO
+
+	var oldReserved bool
+	previous, err := db.postageIndex.Get(addressToItem(addr))
+	switch err {
+	case nil:
+		oldReserved = po >= previous.Depth
+		if err := db.postageChunksIndex.Delete(shed.IndexItem{
+			BatchID:    previous.BatchID,
+			PO:         po,
+			StampIndex: previous.StampIndex,
+		}); err != nil {
+			return err
+		}
+	case leveldb.ErrNotFound:
+	default:
+		return err
+	}
+
+	if err := db.postageIndex.Put(shed.IndexItem{
+		Address:    addr,
+		BatchID:    batchID,
+		StampIndex: stampIndex,
+		Depth:      depth,
+	}); err != nil {
+		return err
+	}
+	if err := db.postageChunksIndex.Put(shed.IndexItem{
+		Address:    addr,
+		BatchID:    batchID,
+		PO:         po,
+		StampIndex: stampIndex,
+	}); err != nil {
+		return err
+	}
+
+	newReserved := po >= depth
+	if err := db.updateReserveMembership(storeItem, oldReserved, newReserved); err != nil {
+		return err
+	}
+	return db.evictExcessReserve()
+}
+
+// SetBatchValue assigns a value to a postage batch, used to rank
+// batches against each other when the reserve must evict chunks to
+// stay within its configured capacity: the lowest-value batch's
+// outermost (furthest, lowest proximity order) chunks are unreserved
+// first. A batch with no value set is treated as having the lowest
+// possible value.
+func (db *DB) SetBatchValue(batchID []byte, value uint64) (err error) {
+	if len(batchID) != batchIDLength {
+		return ErrInvalidBatchID
+	}
+
+	previous, err := db.batchValueIndex.Get(shed.IndexItem{BatchID: batchID})
+	switch err {
+	case nil:
+		if err := db.valueBatchIndex.Delete(shed.IndexItem{Value: previous.Value, BatchID: batchID}); err != nil {
+			return err
+		}
+	case leveldb.ErrNotFound:
+	default:
+		return err
+	}
+
+	if err := db.batchValueIndex.Put(shed.IndexItem{BatchID: batchID, Value: value}); err != nil {
+		return err
+	}
+	return db.valueBatchIndex.Put(shed.IndexItem{Value: value, BatchID: batchID})
+}
+
+// UnreserveBatch updates the neighbourhood depth recorded for every
+// chunk stamped with batchID. Chunks whose proximity order falls
+// below the new depth leave the reserve and become regular gcIndex
+// candidates; they remain retrievable until evicted by the garbage
+// collector. Chunks whose proximity order is still covered by the
+// new depth stay in the reserve.
+func (db *DB) UnreserveBatch(batchID []byte, depth uint8) (err error) {
+	if len(batchID) != batchIDLength {
+		return ErrInvalidBatchID
+	}
+
+	var addrs []chunk.Address
+	err = db.postageChunksIndex.Iterate(func(item shed.IndexItem) (bool, error) {
+		addrs = append(addrs, item.Address)
+		return false, nil
+	}, &shed.IterateOptions{
+		Prefix: batchID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		previous, err := db.postageIndex.Get(addressToItem(addr))
+		if err != nil {
+			return err
+		}
+		storeItem, err := db.get(addr)
+		if err != nil {
+			return err
+		}
+		po := storeItem.PO
+
+		oldReserved := po >= previous.Depth
+		newReserved := po >= depth
+
+		if err := db.postageIndex.Put(shed.IndexItem{
+			Address:    addr,
+			BatchID:    previous.BatchID,
+			StampIndex: previous.StampIndex,
+			Depth:      depth,
+		}); err != nil {
+			return err
+		}
+
+		if err := db.updateReserveMembership(storeItem, oldReserved, newReserved); err != nil {
+			return err
+		}
+	}
+	return db.evictExcessReserve()
+}
+
+// updateReserveMembership keeps a chunk's gcIndex membership in sync
+// with its reserve status (always excluded while reserved, always
+// present as an LRU candidate while not, unless the chunk is pinned,
+// in which case it is left out of gcIndex regardless of reserve
+// status) and adjusts reserveSizeCounter whenever the status
+// actually changes.
+func (db *DB) updateReserveMembership(storeItem shed.IndexItem, oldReserved, newReserved bool) (err error) {
+	gcItem := gcCandidateItem(storeItem)
+	if newReserved {
+		// the chunk is reserved: exclude it from the LRU
+		if err := db.gcIndex.Delete(gcItem); err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+	} else {
+		pinned, err := db.isPinned(storeItem.Address)
+		if err != nil {
+			return err
+		}
+		if !pinned {
+			// the chunk is not reserved: it is a regular LRU candidate
+			if err := db.gcIndex.Put(gcItem); err != nil {
+				return err
+			}
+		}
+	}
+	if oldReserved == newReserved {
+		return nil
+	}
+	if newReserved {
+		return db.incReserveSize()
+	}
+	return db.decReserveSize()
+}
+
+// evictExcessReserve brings the postage reserve back within its
+// configured capacity by repeatedly unreserving chunks from the
+// lowest-value batch's outermost bin until reserveSizeCounter is at
+// or below reserveCapacity. It is a no-op when no capacity was
+// configured.
+func (db *DB) evictExcessReserve() (err error) {
+	if db.reserveCapacity == 0 {
+		return nil
+	}
+	for {
+		size, err := db.reserveSizeCounter.Get()
+		if err != nil {
+			return err
+		}
+		if size <= db.reserveCapacity {
+			return nil
+		}
+
+		batchID, ok, err := db.lowestValueBatch()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// no batch left to unreserve from
+			return nil
+		}
+
+		addr, ok, err := db.outermostReservedChunk(batchID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// the lowest-value batch has no more reserved chunks; it
+			// no longer competes for reserve space until its value is
+			// set again
+			if err := db.removeBatchValue(batchID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := db.unreserveChunk(addr); err != nil {
+			return err
+		}
+	}
+}
+
+// lowestValueBatch returns the batch ID with the smallest value set
+// via SetBatchValue. ok is false if no batch has a value set.
+func (db *DB) lowestValueBatch() (batchID []byte, ok bool, err error) {
+	err = db.valueBatchIndex.IterateAll(func(item shed.IndexItem) (bool, error) {
+		batchID = item.BatchID
+		ok = true
+		return true, nil
+	})
+	return batchID, ok, err
+}
+
+// outermostReservedChunk walks batchID's chunks in proximity order,
+// closest to the base key last, returning the address of the first
+// one that is still reserved. ok is false if every chunk stamped
+// with batchID has already left the reserve.
+func (db *DB) outermostReservedChunk(batchID []byte) (addr chunk.Address, ok bool, err error) {
+	err = db.postageChunksIndex.Iterate(func(item shed.IndexItem) (bool, error) {
+		previous, err := db.postageIndex.Get(addressToItem(item.Address))
+		if err != nil {
+			return false, err
+		}
+		if item.PO < previous.Depth {
+			// already unreserved, either by a previous eviction or by
+			// UnreserveBatch
+			return false, nil
+		}
+		addr = item.Address
+		ok = true
+		return true, nil
+	}, &shed.IterateOptions{
+		Prefix: batchID,
+	})
+	return addr, ok, err
+}
+
+// unreserveChunk forces addr out of the postage reserve, regardless
+// of its batch's depth, by tightening its own stamp depth to just
+// past its proximity order, and makes it a regular gcIndex candidate
+// unless it is pinned. It is used by evictExcessReserve to make room
+// when the reserve has grown past its configured capacity.
+func (db *DB) unreserveChunk(addr chunk.Address) (err error) {
+	storeItem, err := db.get(addr)
+	if err != nil {
+		return err
+	}
+	previous, err := db.postageIndex.Get(addressToItem(addr))
+	if err != nil {
+		return err
+	}
+
+	newDepth := storeItem.PO
+	if newDepth < 255 {
+		newDepth++
+	}
+	if err := db.postageIndex.Put(shed.IndexItem{
+		Address:    addr,
+		BatchID:    previous.BatchID,
+		StampIndex: previous.StampIndex,
+		Depth:      newDepth,
+	}); err != nil {
+		return err
+	}
+
+	pinned, err := db.isPinned(addr)
+	if err != nil {
+		return err
+	}
+	if !pinned {
+		if err := db.gcIndex.Put(gcCandidateItem(storeItem)); err != nil {
+			return err
+		}
+	}
+
+	return db.decReserveSize()
+}
+
+// removeBatchValue drops batchID from consideration when selecting
+// the lowest-value batch to evict from. It does not affect the
+// batch's stamps; SetBatchValue can be called again to re-enter it.
+func (db *DB) removeBatchValue(batchID []byte) (err error) {
+	previous, err := db.batchValueIndex.Get(shed.IndexItem{BatchID: batchID})
+	if err != nil {
+		return err
+	}
+	if err := db.valueBatchIndex.Delete(shed.IndexItem{Value: previous.Value, BatchID: batchID}); err != nil {
+		return err
+	}
+	return db.batchValueIndex.Delete(shed.IndexItem{BatchID: batchID})
+}
+
+// ReserveSize returns the number of chunks currently held in the
+// postage reserve.
+func (db *DB) ReserveSize() (uint64, error) {
+	return db.reserveSizeCounter.Get()
+}
+
+// incReserveSize increments the persisted reserve chunk counter.
+func (db *DB) incReserveSize() (err error) {
+	size, err := db.reserveSizeCounter.Get()
+	if err != nil {
+		return err
+	}
+	return db.reserveSizeCounter.Put(size + 1)
+}
+
+// decReserveSize decrements the persisted reserve chunk counter.
+func (db *DB) decReserveSize() (err error) {
+	size, err := db.reserveSizeCounter.Get()
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	return db.reserveSizeCounter.Put(size - 1)
+}
@@ -0,0 +1,373 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// newTestBatchID returns a random postage batch identifier, to be
+// used in tests.
+func newTestBatchID(t *testing.T) []byte {
+	t.Helper()
+	batchID := make([]byte, batchIDLength)
+	if _, err := rand.Read(batchID); err != nil {
+		t.Fatal(err)
+	}
+	return batchID
+}
+
+// TestSetPostageStamp validates that stamping a chunk within its
+// batch's depth reserves it, excluding it from gcIndex and counting
+// it in ReserveSize, while stamping a chunk outside the depth leaves
+// it as a regular gcIndex candidate.
+func TestSetPostageStamp(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	getter := db.NewGetter(chunk.ModeGetRequest)
+	batchID := newTestBatchID(t)
+
+	t.Run("stamp within depth reserves the chunk", func(t *testing.T) {
+		ch := mustStoreChunkInBin(t, db, uploader, 0)
+
+		if err := db.SetPostageStamp(ch.Address(), batchID, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := db.ReserveSize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != 1 {
+			t.Errorf("got reserve size %v, want 1", size)
+		}
+
+		err = db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+			if bytes.Equal(item.Address, ch.Address()) {
+				t.Errorf("reserved chunk address %x found in gc index", item.Address)
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("stamp outside depth leaves the chunk in gc index", func(t *testing.T) {
+		ch := mustStoreChunkInBin(t, db, uploader, 0)
+
+		// a depth of 1 requires proximity order 1, which bin 0 never
+		// satisfies
+		if err := db.SetPostageStamp(ch.Address(), batchID, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := db.ReserveSize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != 1 {
+			t.Errorf("got reserve size %v, want 1 (unchanged)", size)
+		}
+
+		var found bool
+		err = db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+			if bytes.Equal(item.Address, ch.Address()) {
+				found = true
+			}
+			return false, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found {
+			t.Error("unreserved stamped chunk not found in gc index")
+		}
+
+		// the chunk is still retrievable, as it is only a gc
+		// candidate, not removed
+		if _, err := getter.Get(context.Background(), ch.Address()); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// TestUnreserveBatch validates that growing a batch's depth moves the
+// chunks it no longer covers out of the reserve and into gcIndex,
+// while chunks still within the new depth remain reserved.
+func TestUnreserveBatch(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	getter := db.NewGetter(chunk.ModeGetRequest)
+	batchID := newTestBatchID(t)
+
+	outer := mustStoreChunkInBin(t, db, uploader, 0)
+	inner := mustStoreChunkInBin(t, db, uploader, 1)
+
+	for i, c := range []struct {
+		addr  chunk.Address
+		index uint64
+	}{
+		{outer.Address(), 0},
+		{inner.Address(), 1},
+	} {
+		if err := db.SetPostageStamp(c.addr, batchID, c.index, 0); err != nil {
+			t.Fatalf("stamp %v: %v", i, err)
+		}
+	}
+
+	size, err := db.ReserveSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 2 {
+		t.Fatalf("got reserve size %v, want 2", size)
+	}
+
+	// raising the depth to 1 unreserves the outer (bin 0) chunk, but
+	// not the inner (bin 1) chunk
+	if err := db.UnreserveBatch(batchID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err = db.ReserveSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 1 {
+		t.Errorf("got reserve size %v, want 1", size)
+	}
+
+	var foundOuter, foundInner bool
+	err = db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+		if bytes.Equal(item.Address, outer.Address()) {
+			foundOuter = true
+		}
+		if bytes.Equal(item.Address, inner.Address()) {
+			foundInner = true
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !foundOuter {
+		t.Error("unreserved outer chunk not found in gc index")
+	}
+	if foundInner {
+		t.Error("still-reserved inner chunk found in gc index")
+	}
+
+	// both chunks remain retrievable, the outer one only pending
+	// eventual LRU eviction
+	if _, err := getter.Get(context.Background(), outer.Address()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := getter.Get(context.Background(), inner.Address()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestUnreserveBatch_pinned validates that a pinned chunk leaving the
+// reserve is not reinserted into gcIndex, preserving its GC
+// exclusion.
+func TestUnreserveBatch_pinned(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	batchID := newTestBatchID(t)
+
+	ch := mustStoreChunkInBin(t, db, uploader, 0)
+	if err := db.SetPostageStamp(ch.Address(), batchID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetPin, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	// depth 1 pushes the bin 0 chunk out of the reserve
+	if err := db.UnreserveBatch(batchID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+		if bytes.Equal(item.Address, ch.Address()) {
+			t.Errorf("pinned chunk address %x found in gc index", item.Address)
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReserveCapacity_evictsLowestValueBatch validates that once a
+// postage stamp pushes the reserve past its configured capacity, the
+// outermost chunk of the lowest-value batch is unreserved to bring
+// it back within capacity, leaving higher-value batches untouched.
+func TestReserveCapacity_evictsLowestValueBatch(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, &Options{ReserveCapacity: 2})
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+
+	cheapBatch := newTestBatchID(t)
+	pricyBatch := newTestBatchID(t)
+	if err := db.SetBatchValue(cheapBatch, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetBatchValue(pricyBatch, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	cheapOuter := mustStoreChunkInBin(t, db, uploader, 0)
+	cheapInner := mustStoreChunkInBin(t, db, uploader, 1)
+	if err := db.SetPostageStamp(cheapOuter.Address(), cheapBatch, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetPostageStamp(cheapInner.Address(), cheapBatch, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := db.ReserveSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 2 {
+		t.Fatalf("got reserve size %v, want 2", size)
+	}
+
+	// a third reserved chunk, from the pricier batch, exceeds capacity
+	pricyChunk := mustStoreChunkInBin(t, db, uploader, 0)
+	if err := db.SetPostageStamp(pricyChunk.Address(), pricyBatch, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err = db.ReserveSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 2 {
+		t.Errorf("got reserve size %v, want 2 (capacity enforced)", size)
+	}
+
+	var foundCheapOuter, foundCheapInner, foundPricy bool
+	err = db.gcIndex.IterateAll(func(item shed.IndexItem) (stop bool, err error) {
+		switch {
+		case bytes.Equal(item.Address, cheapOuter.Address()):
+			foundCheapOuter = true
+		case bytes.Equal(item.Address, cheapInner.Address()):
+			foundCheapInner = true
+		case bytes.Equal(item.Address, pricyChunk.Address()):
+			foundPricy = true
+		}
+		return false, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !foundCheapOuter {
+		t.Error("outermost chunk of the lowest-value batch was not evicted to gc index")
+	}
+	if foundCheapInner {
+		t.Error("inner chunk of the lowest-value batch was evicted before its outer chunk")
+	}
+	if foundPricy {
+		t.Error("chunk of the higher-value batch was evicted")
+	}
+}
+
+// TestReserveCapacity_evictsNeverAccessed validates that a chunk
+// forced out of the reserve by evictExcessReserve, having been
+// uploaded but never accessed, is reinserted into gcIndex keyed by
+// its StoreTimestamp as the access timestamp, the same key setSync
+// and updateReserveMembership already use for the identical
+// situation, rather than a zero access timestamp.
+func TestReserveCapacity_evictsNeverAccessed(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, &Options{ReserveCapacity: 0})
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	batchID := newTestBatchID(t)
+
+	ch := mustStoreChunkInBin(t, db, uploader, 0)
+	if err := db.SetPostageStamp(ch.Address(), batchID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	storeItem, err := db.get(ch.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if storeItem.AccessTimestamp != 0 {
+		t.Fatalf("test chunk unexpectedly has a non-zero access timestamp: %v", storeItem.AccessTimestamp)
+	}
+
+	if err := db.unreserveChunk(ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.gcIndex.Get(shed.IndexItem{
+		Address:         ch.Address(),
+		StoreTimestamp:  storeItem.StoreTimestamp,
+		AccessTimestamp: storeItem.StoreTimestamp,
+	}); err != nil {
+		t.Fatalf("gc index entry not keyed by store timestamp: %v", err)
+	}
+}
+
+// TestModeSetRemove_postageStamp validates that removing a reserved
+// chunk decrements ReserveSize and cleans up its postage stamp.
+func TestModeSetRemove_postageStamp(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+	ch := mustStoreChunkInBin(t, db, uploader, 0)
+	batchID := newTestBatchID(t)
+
+	if err := db.SetPostageStamp(ch.Address(), batchID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Set(context.Background(), chunk.ModeSetRemove, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := db.ReserveSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 0 {
+		t.Errorf("got reserve size %v, want 0", size)
+	}
+
+	if _, err := db.postageIndex.Get(addressToItem(ch.Address())); err == nil {
+		t.Error("got no error, want leveldb.ErrNotFound")
+	}
+}
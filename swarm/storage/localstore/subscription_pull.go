@@ -0,0 +1,114 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// ChunkDescriptor holds the address and BinID of a chunk as emitted
+// by SubscribePull, giving the syncer a stable cursor to resume from.
+type ChunkDescriptor struct {
+	Address chunk.Address
+	BinID   uint64
+}
+
+// SubscribePull returns a channel that emits ChunkDescriptors for
+// every chunk stored in proximity order bin, starting at since and
+// stopping once until is reached, if until is not zero. If until is
+// zero, the channel keeps emitting newly stored chunks until ctx is
+// done or the returned stop function is called.
+func (db *DB) SubscribePull(ctx context.Context, bin uint8, since, until uint64) (c <-chan ChunkDescriptor, stop func()) {
+	chunkDescriptors := make(chan ChunkDescriptor)
+	trigger, unsubscribe := db.subscribePullTrigger(bin)
+
+	stopChan := make(chan struct{})
+	var stopped bool
+	var stopMu sync.Mutex
+	stop = func() {
+		stopMu.Lock()
+		defer stopMu.Unlock()
+		if !stopped {
+			close(stopChan)
+			stopped = true
+		}
+		unsubscribe()
+	}
+
+	go func() {
+		defer unsubscribe()
+
+		// since is exclusive: resume after the last delivered item
+		current := since
+		for {
+			err := db.pullIndex.Iterate(func(item shed.IndexItem) (bool, error) {
+				select {
+				case chunkDescriptors <- ChunkDescriptor{
+					Address: item.Address,
+					BinID:   item.BinID,
+				}:
+				case <-ctx.Done():
+					return true, ctx.Err()
+				case <-stopChan:
+					return true, nil
+				}
+				current = item.BinID + 1
+				if until > 0 && item.BinID >= until {
+					return true, errStopIteration
+				}
+				return false, nil
+			}, &shed.IterateOptions{
+				StartFrom: &shed.IndexItem{
+					PO:    bin,
+					BinID: current,
+				},
+				Prefix: []byte{bin},
+			})
+			if err == errStopIteration || (until > 0 && current > until) {
+				return
+			}
+			if err != nil {
+				return
+			}
+
+			select {
+			case <-trigger:
+				// new data has been stored since the last
+				// iteration, loop around to pick it up
+			case <-ctx.Done():
+				return
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+
+	return chunkDescriptors, stop
+}
+
+// errStopIteration is a sentinel error used internally to stop an
+// Index.Iterate call once the requested upper bound has been
+// reached.
+var errStopIteration = stopIterationError{}
+
+type stopIterationError struct{}
+
+func (stopIterationError) Error() string { return "localstore: stop iteration" }
@@ -0,0 +1,129 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+	"github.com/ethereum/go-ethereum/swarm/shed"
+)
+
+// SubscribePush returns a channel that emits every chunk stored
+// locally through ModePutUpload, in the order they were uploaded, so
+// that an uploader can stream them out to their neighbourhoods. A
+// chunk is removed from the feed once it has been confirmed synced
+// through ModeSetSync.
+func (db *DB) SubscribePush(ctx context.Context) (c <-chan chunk.Chunk, stop func()) {
+	chunks := make(chan chunk.Chunk)
+	trigger, unsubscribe := db.subscribePushTrigger()
+
+	stopChan := make(chan struct{})
+	var stopped bool
+	var stopMu sync.Mutex
+	stop = func() {
+		stopMu.Lock()
+		defer stopMu.Unlock()
+		if !stopped {
+			close(stopChan)
+			stopped = true
+		}
+		unsubscribe()
+	}
+
+	go func() {
+		defer unsubscribe()
+
+		since := &shed.IndexItem{}
+		for {
+			var last *shed.IndexItem
+			err := db.pushIndex.Iterate(func(item shed.IndexItem) (bool, error) {
+				data, err := db.get(item.Address)
+				if err != nil {
+					return true, err
+				}
+				select {
+				case chunks <- chunk.NewChunk(item.Address, data.Data):
+				case <-ctx.Done():
+					return true, ctx.Err()
+				case <-stopChan:
+					return true, nil
+				}
+				last = &item
+				return false, nil
+			}, &shed.IterateOptions{
+				StartFrom:         since,
+				SkipStartFromItem: true,
+			})
+			if err != nil {
+				return
+			}
+			if last != nil {
+				since = last
+			}
+
+			select {
+			case <-trigger:
+			case <-ctx.Done():
+				return
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+
+	return chunks, stop
+}
+
+// subscribePushTrigger registers a new trigger channel, returning it
+// together with a function that removes it again. Triggers are fed
+// by putUpload whenever a new chunk is pushed.
+func (db *DB) subscribePushTrigger() (c chan struct{}, unsubscribe func()) {
+	c = make(chan struct{}, 1)
+
+	db.pushTriggersMu.Lock()
+	db.pushTriggers = append(db.pushTriggers, c)
+	db.pushTriggersMu.Unlock()
+
+	return c, func() {
+		db.pushTriggersMu.Lock()
+		defer db.pushTriggersMu.Unlock()
+
+		triggers := db.pushTriggers
+		for i, t := range triggers {
+			if t == c {
+				db.pushTriggers = append(triggers[:i], triggers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// triggerPushSubscriptions notifies all registered push trigger
+// channels that a new chunk has been stored. Sends are non-blocking.
+func (db *DB) triggerPushSubscriptions() {
+	db.pushTriggersMu.Lock()
+	defer db.pushTriggersMu.Unlock()
+
+	for _, c := range db.pushTriggers {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package localstore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/swarm/chunk"
+)
+
+// TestDB_SubscribePush validates that every locally uploaded chunk is
+// streamed out over SubscribePush exactly once, interleaving uploads
+// with the consumer reading from the channel.
+func TestDB_SubscribePush(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunksCh, stop := db.SubscribePush(ctx)
+	defer stop()
+
+	ch := generateRandomChunk()
+	if err := uploader.Put(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-chunksCh:
+		if !bytes.Equal([]byte(got.Address()), []byte(ch.Address())) {
+			t.Errorf("got chunk address %x, want %x", []byte(got.Address()), []byte(ch.Address()))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for pushed chunk")
+	}
+
+	var more []chunk.Chunk
+	for i := 0; i < 5; i++ {
+		c := generateRandomChunk()
+		if err := uploader.Put(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		more = append(more, c)
+	}
+
+	for i, want := range more {
+		select {
+		case got := <-chunksCh:
+			if !bytes.Equal([]byte(got.Address()), []byte(want.Address())) {
+				t.Errorf("got chunk %v address %x, want %x", i, []byte(got.Address()), []byte(want.Address()))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for pushed chunk %v", i)
+		}
+	}
+}
+
+// TestDB_SubscribePush_synced validates that a chunk confirmed via
+// ModeSetSync is not re-emitted by a subscription started afterwards.
+func TestDB_SubscribePush_synced(t *testing.T) {
+	db, cleanupFunc := newTestDB(t, nil)
+	defer cleanupFunc()
+
+	uploader := db.NewPutter(chunk.ModePutUpload, nil)
+
+	ch := generateRandomChunk()
+	if err := uploader.Put(context.Background(), ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Set(context.Background(), chunk.ModeSetSync, ch.Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	other := generateRandomChunk()
+	if err := uploader.Put(context.Background(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	chunksCh, stop := db.SubscribePush(ctx)
+	defer stop()
+
+	select {
+	case got := <-chunksCh:
+		if !bytes.Equal([]byte(got.Address()), []byte(other.Address())) {
+			t.Errorf("got chunk address %x, want %x", []byte(got.Address()), []byte(other.Address()))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for pushed chunk")
+	}
+
+	select {
+	case got := <-chunksCh:
+		t.Fatalf("got unexpected chunk %x, synced chunk should not be pushed again", []byte(got.Address()))
+	case <-time.After(200 * time.Millisecond):
+	}
+}